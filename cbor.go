@@ -0,0 +1,324 @@
+package rfc6902
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+var (
+	errCBORTruncated = errors.New("rfc6902: truncated CBOR document")
+	errCBORMalformed = errors.New("rfc6902: malformed CBOR document")
+)
+
+/*
+cborCodec reads and writes CBOR (RFC 8949).
+
+Integers and floats are read as float64 and integral values are written back
+as integers.  Integers, bignums included, a float64 cannot hold exactly are
+rejected rather than rounded.  Byte strings are read as []byte, tags other than bignums are
+ignored and undefined is read as nil.
+*/
+type cborCodec struct{}
+
+func (cborCodec) Decode(b []byte) (interface{}, error) {
+	if len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty CBOR document")
+	}
+	d := &cborDecoder{b: b}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(b) {
+		return nil, errors.New("rfc6902: trailing data after CBOR document")
+	}
+	return v, nil
+}
+
+func (cborCodec) MediaType() string { return "application/cbor" }
+
+func (cborCodec) Encode(v interface{}) ([]byte, error) {
+	return cborAppend(nil, v)
+}
+
+type cborDecoder struct {
+	b   []byte
+	off int
+}
+
+// indefinite is the argument of a head with additional information 31.
+const indefinite = math.MaxUint64
+
+// head reads an initial byte and its argument.
+func (d *cborDecoder) head() (major byte, arg uint64, err error) {
+	if d.off >= len(d.b) {
+		return 0, 0, errCBORTruncated
+	}
+	c := d.b[d.off]
+	d.off++
+	major, info := c>>5, c&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if d.off+n > len(d.b) {
+			return 0, 0, errCBORTruncated
+		}
+		for _, b := range d.b[d.off : d.off+n] {
+			arg = arg<<8 | uint64(b)
+		}
+		d.off += n
+		return major, arg, nil
+	case info == 31 && major >= 2 && major != 6:
+		return major, indefinite, nil
+	}
+	return 0, 0, errCBORMalformed
+}
+
+// more reports whether an indefinite length item continues, consuming its
+// break code if not.
+func (d *cborDecoder) more() (bool, error) {
+	if d.off >= len(d.b) {
+		return false, errCBORTruncated
+	}
+	if d.b[d.off] == 0xff {
+		d.off++
+		return false, nil
+	}
+	return true, nil
+}
+
+// count checks that n items of at least one byte each can follow.
+func (d *cborDecoder) count(n uint64) (int, error) {
+	if n > uint64(len(d.b)-d.off) {
+		return 0, errCBORTruncated
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, errors.New("rfc6902: CBOR document nested too deeply")
+	}
+	start := d.off
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		return fromUint(arg)
+	case 1:
+		if arg < 1<<53 {
+			return -1 - float64(arg), nil
+		}
+		// -1 - arg
+		return fromBig(new(big.Int).Not(new(big.Int).SetUint64(arg)))
+
+	case 2, 3:
+		s, err := d.str(major, arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == 2 {
+			return s, nil
+		}
+		return string(s), nil
+
+	case 4:
+		if arg != indefinite {
+			if _, err := d.count(arg); err != nil {
+				return nil, err
+			}
+		}
+		a := make([]interface{}, 0)
+		for i := uint64(0); arg == indefinite || i < arg; i++ {
+			if arg == indefinite {
+				if ok, err := d.more(); !ok || err != nil {
+					return a, err
+				}
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+
+	case 5:
+		if arg != indefinite {
+			if _, err := d.count(arg); err != nil {
+				return nil, err
+			}
+		}
+		m := make(map[string]interface{})
+		for i := uint64(0); arg == indefinite || i < arg; i++ {
+			if arg == indefinite {
+				if ok, err := d.more(); !ok || err != nil {
+					return m, err
+				}
+			}
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			key, err := mapKey(k)
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case 6:
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := v.([]byte); ok && (arg == 2 || arg == 3) {
+			i := new(big.Int).SetBytes(b)
+			if arg == 3 {
+				i.Not(i)
+			}
+			return fromBig(i)
+		}
+		return v, nil
+	}
+
+	// major type 7, the argument holds the simple value or float bits
+	switch d.b[start] & 0x1f {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return halfFloat(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	}
+	return nil, fmt.Errorf("rfc6902: unsupported CBOR simple value %d", arg)
+}
+
+// str reads the content of a byte or text string.
+func (d *cborDecoder) str(major byte, arg uint64) ([]byte, error) {
+	if arg != indefinite {
+		n, err := d.count(arg)
+		if err != nil {
+			return nil, err
+		}
+		s := make([]byte, n)
+		copy(s, d.b[d.off:])
+		d.off += n
+		return s, nil
+	}
+	s := make([]byte, 0)
+	for {
+		if ok, err := d.more(); !ok || err != nil {
+			return s, err
+		}
+		m, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || n == indefinite {
+			return nil, errCBORMalformed
+		}
+		chunk, err := d.str(major, n)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+func halfFloat(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+func cborHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, m|27), n)
+}
+
+// cborAppend appends the encoding of v to b.  Map keys are written in sorted
+// order.
+func cborAppend(b []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xf6), nil
+	case bool:
+		if x {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case float64:
+		switch {
+		case integral(x) && x >= 0:
+			return cborHead(b, 0, uint64(x)), nil
+		case integral(x):
+			return cborHead(b, 1, uint64(-1-x)), nil
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(x)), nil
+	case []byte:
+		return append(cborHead(b, 2, uint64(len(x))), x...), nil
+	case string:
+		return append(cborHead(b, 3, uint64(len(x))), x...), nil
+	case []interface{}:
+		b = cborHead(b, 4, uint64(len(x)))
+		for _, e := range x {
+			if b, err = cborAppend(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		b = cborHead(b, 5, uint64(len(x)))
+		for _, k := range sortedKeys(x) {
+			b = append(cborHead(b, 3, uint64(len(k))), k...)
+			if b, err = cborAppend(b, x[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("rfc6902: cannot encode %T as CBOR", v)
+}
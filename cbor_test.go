@@ -0,0 +1,130 @@
+package rfc6902
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// examples from RFC 8949 appendix A
+func Test_CBOR_Decode(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected interface{}
+	}{
+		{"00", 0.0},
+		{"17", 23.0},
+		{"1818", 24.0},
+		{"1903e8", 1000.0},
+		{"1b000000e8d4a51000", 1000000000000.0},
+		{"1b0020000000000002", 9007199254740994.0},
+		{"c249010000000000000000", 18446744073709551616.0},
+		{"3bffffffffffffffff", -18446744073709551616.0},
+		{"3863", -100.0},
+		{"f90000", 0.0},
+		{"f93c00", 1.0},
+		{"f93e00", 1.5},
+		{"f97bff", 65504.0},
+		{"f90001", 5.960464477539063e-8},
+		{"f9c400", -4.0},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", nil},
+		{"c074323031332d30332d32315432303a30343a30305a", "2013-03-21T20:04:00Z"},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"62c3bc", "ü"},
+		{"83010203", []interface{}{1.0, 2.0, 3.0}},
+		{"a201020304", map[string]interface{}{"1": 2.0, "3": 4.0}},
+		{"a26161016162820203", map[string]interface{}{"a": 1.0, "b": []interface{}{2.0, 3.0}}},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []interface{}{1.0, []interface{}{2.0, 3.0}, []interface{}{4.0, 5.0}}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": 1.0, "b": []interface{}{2.0, 3.0}}},
+	}
+
+	for _, test := range tests {
+		actual, err := CBOR.Decode(unhex(test.in))
+		if err != nil {
+			t.Errorf("%s: Decode failed: %s", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: (actual) %#v != %#v (expected)", test.in, actual, test.expected)
+		}
+	}
+
+	if v, _ := CBOR.Decode(unhex("f97c00")); v != math.Inf(1) {
+		t.Errorf("f97c00: (actual) %v != +Inf (expected)", v)
+	}
+}
+
+func Test_CBOR_DecodeErrors(t *testing.T) {
+	for _, in := range []string{"", "18", "1a0000", "62c3", "830102", "a1", "5f4101", "5f6101ff", "ff", "1c", "a18101f6", "0000", "9bffffffffffffffff",
+		// integers a float64 cannot hold exactly
+		"1bffffffffffffffff", "1b0020000000000001", "3b0020000000000002", "c249010000000000000001"} {
+		if _, err := CBOR.Decode(unhex(in)); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func Test_CBOR_Encode(t *testing.T) {
+	tests := []struct {
+		in       interface{}
+		expected string
+	}{
+		{0.0, "00"},
+		{1000.0, "1903e8"},
+		{-100.0, "3863"},
+		{1.5, "fb3ff8000000000000"},
+		{nil, "f6"},
+		{true, "f5"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2}, "420102"},
+		{[]interface{}{1.0, []interface{}{2.0}}, "82018102"},
+		{map[string]interface{}{"b": 2.0, "a": 1.0}, "a2616101616202"},
+	}
+	for _, test := range tests {
+		actual, err := CBOR.Encode(test.in)
+		if err != nil {
+			t.Errorf("%v: Encode failed: %s", test.in, err)
+			continue
+		}
+		if hex.EncodeToString(actual) != test.expected {
+			t.Errorf("%v: (actual) %x != %s (expected)", test.in, actual, test.expected)
+		}
+	}
+}
+
+func Test_CBOR_Apply(t *testing.T) {
+	// {1: "one", "data": h'0102', "n": 1}
+	doc := unhex("a301636f6e656464617461420102616e01")
+	p, _ := ParsePatch(strings.NewReader(`[
+		{"op": "test", "path": "/data", "value": "\u0001\u0002"},
+		{"op": "move", "from": "/1", "path": "/one"},
+		{"op": "replace", "path": "/n", "value": 2}
+	]`))
+	actual, err := p.ApplyWithOptions(doc, &ApplyOptions{Codec: CBOR})
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	v, _ := CBOR.Decode(actual)
+	expected := map[string]interface{}{"one": "one", "data": []byte{1, 2}, "n": 2.0}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("(actual) %#v != %#v (expected)", v, expected)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Command rfc6902 applies, explains and lints JSON Patch documents.
+
+Usage:
+
+	rfc6902 apply [-yaml] [-canonical] PATCH [DOCUMENT]
+	rfc6902 explain [-format plain|ansi|markdown] PATCH [DOCUMENT]
+	rfc6902 lint [-optimize] PATCH [DOCUMENT]
+
+apply reads DOCUMENT, or standard input, patches it and writes the result to
+standard output.  With -yaml, or when DOCUMENT ends in .yaml or .yml, the
+document is read and written as YAML, keeping its comments and key order, and
+the patch may be written in YAML as well.  With -canonical a JSON result is
+written in its canonical form (RFC 8785).
+
+explain writes how PATCH changes DOCUMENT, or standard input, as a unified
+diff, in plain text, colored for a terminal or as a Markdown code block.
+
+lint writes the issues found in PATCH, one per line, and fails if one is an
+error.  With -optimize it instead writes the shortest patch it finds doing the
+same to DOCUMENT, or standard input.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/noahcampbell/rfc6902"
+	"github.com/noahcampbell/rfc6902/yamlpatch"
+)
+
+const usage = `usage: rfc6902 apply [-yaml] [-canonical] PATCH [DOCUMENT]
+       rfc6902 explain [-format plain|ansi|markdown] PATCH [DOCUMENT]
+       rfc6902 lint [-optimize] PATCH [DOCUMENT]`
+
+var commands = map[string]func(args []string, stdin io.Reader, stdout io.Writer) error{
+	"apply":   apply,
+	"explain": explain,
+	"lint":    lint,
+}
+
+var formats = map[string]rfc6902.Format{
+	"plain":    rfc6902.FormatPlain,
+	"ansi":     rfc6902.FormatANSI,
+	"markdown": rfc6902.FormatMarkdown,
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("rfc6902: unknown command %q\n%s", args[0], usage)
+	}
+	return cmd(args[1:], stdin, stdout)
+}
+
+func apply(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	yamlMode := fs.Bool("yaml", false, "read and write YAML documents")
+	canonical := fs.Bool("canonical", false, "write canonical JSON (RFC 8785)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return errors.New(usage)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	in := stdin
+	if fs.NArg() == 2 {
+		ext := filepath.Ext(fs.Arg(1))
+		*yamlMode = *yamlMode || ext == ".yaml" || ext == ".yml"
+		doc, err := os.Open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer doc.Close()
+		in = doc
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	var p *rfc6902.Patcher
+	if *yamlMode {
+		p, err = yamlpatch.ParsePatch(f)
+	} else {
+		p, err = rfc6902.ParsePatch(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if *yamlMode {
+		out, err = yamlpatch.Apply(p, b)
+	} else {
+		o := new(rfc6902.ApplyOptions)
+		if *canonical {
+			o.Codec = rfc6902.CanonicalJSON
+		}
+		if out, err = p.ApplyWithOptions(b, o); err == nil {
+			out = append(out, '\n')
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+func explain(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	name := fs.String("format", "plain", "output format: plain, ansi or markdown")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, ok := formats[*name]
+	if !ok || fs.NArg() < 1 || fs.NArg() > 2 {
+		return errors.New(usage)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p, err := rfc6902.ParsePatch(f)
+	if err != nil {
+		return err
+	}
+
+	in := stdin
+	if fs.NArg() == 2 {
+		doc, err := os.Open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer doc.Close()
+		in = doc
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	out, err := p.ExplainAs(b, format)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, out)
+	return err
+}
+
+func lint(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	optimize := fs.Bool("optimize", false, "write an optimized patch for DOCUMENT")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 || (!*optimize && fs.NArg() > 1) {
+		return errors.New(usage)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p, err := rfc6902.ParsePatch(f)
+	if err != nil {
+		return err
+	}
+
+	if !*optimize {
+		errs := 0
+		for _, finding := range rfc6902.Lint(p) {
+			if finding.Severity == rfc6902.SeverityError {
+				errs++
+			}
+			if _, err := fmt.Fprintln(stdout, finding); err != nil {
+				return err
+			}
+		}
+		if errs > 0 {
+			return fmt.Errorf("rfc6902: %d errors in %s", errs, fs.Arg(0))
+		}
+		return nil
+	}
+
+	in := stdin
+	if fs.NArg() == 2 {
+		doc, err := os.Open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer doc.Close()
+		in = doc
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if p, err = rfc6902.Optimize(p, b); err != nil {
+		return err
+	}
+	out, err := p.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_Apply(t *testing.T) {
+	jsonPatch := writeFile(t, "patch.json", `[{"op": "replace", "path": "/a", "value": 2}]`)
+	yamlPatch := writeFile(t, "patch.yaml", "- op: replace\n  path: /a\n  value: 2\n")
+	yamlDoc := writeFile(t, "doc.yaml", "a: 1 # one\nb: x\n")
+
+	tests := []struct {
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{[]string{"apply", jsonPatch}, `{"a": 1}`, "{\"a\":2}\n"},
+		{[]string{"apply", "-canonical", jsonPatch}, `{"b": "<", "a": 1}`, "{\"a\":2,\"b\":\"<\"}\n"},
+		{[]string{"apply", "-yaml", yamlPatch}, "a: 1 # one\n", "a: 2 # one\n"},
+		{[]string{"apply", yamlPatch, yamlDoc}, "", "a: 2 # one\nb: x\n"},
+	}
+	for i, test := range tests {
+		out := new(bytes.Buffer)
+		if err := run(test.args, strings.NewReader(test.stdin), out); err != nil {
+			t.Errorf("%d: run failed: %s", i, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, out, test.expected)
+		}
+	}
+}
+
+func Test_Explain(t *testing.T) {
+	patch := writeFile(t, "patch.json", `[{"op": "replace", "path": "/a", "value": 2}]`)
+	doc := writeFile(t, "doc.json", `{"a": 1}`)
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"explain", patch, doc}, "@@ replace /a @@\n- 1\n+ 2\n"},
+		{[]string{"explain", "-format", "markdown", patch}, "```diff\n@@ replace /a @@\n- 1\n+ 2\n```\n"},
+	}
+	for i, test := range tests {
+		out := new(bytes.Buffer)
+		if err := run(test.args, strings.NewReader(`{"a": 1}`), out); err != nil {
+			t.Errorf("%d: run failed: %s", i, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, out, test.expected)
+		}
+	}
+}
+
+func Test_Lint(t *testing.T) {
+	patch := writeFile(t, "patch.json", `[{"op": "replace", "path": "/a", "value": 2}, {"op": "replace", "path": "/a", "value": 3}]`)
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"lint", patch}, "0: warning: value written to /a is overwritten by 1\n"},
+		{[]string{"lint", "-optimize", patch}, "[{\"op\":\"replace\",\"path\":\"/a\",\"value\":3}]\n"},
+	}
+	for i, test := range tests {
+		out := new(bytes.Buffer)
+		if err := run(test.args, strings.NewReader(`{"a": 1}`), out); err != nil {
+			t.Errorf("%d: run failed: %s", i, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, out, test.expected)
+		}
+	}
+}
+
+func Test_Run_Errors(t *testing.T) {
+	patch := writeFile(t, "patch.json", `[{"op": "remove", "path": "/x"}]`)
+	bad := writeFile(t, "bad.json", `[{"op": "remove", "path": "/x/-"}]`)
+	for i, args := range [][]string{
+		{},
+		{"frobnicate"},
+		{"apply"},
+		{"apply", filepath.Join(t.TempDir(), "missing.json")},
+		{"apply", patch},
+		{"explain", patch},
+		{"explain", "-format", "html", patch},
+		{"lint", bad},
+		{"lint", patch, patch},
+		{"lint", "-optimize", patch},
+	} {
+		if err := run(args, strings.NewReader(`{}`), new(bytes.Buffer)); err == nil {
+			t.Errorf("%d: expected an error for %q", i, args)
+		}
+	}
+}
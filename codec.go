@@ -0,0 +1,152 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+/*
+A Codec reads documents into the tree patched by Patcher and writes them back.
+
+The tree holds the values encoding/json decodes to: map[string]interface{},
+[]interface{}, string, float64, bool and nil.  Binary formats may add []byte
+for binary strings; a test operation compares them to the string holding the
+same bytes.
+
+A Codec may have a MediaType() string method returning the media type of the
+documents it writes, used by PatchHandler.  Codecs without one are assumed to
+write JSON.
+*/
+type Codec interface {
+	Decode(b []byte) (interface{}, error)
+	Encode(v interface{}) ([]byte, error)
+}
+
+// mediaType returns the media type of the documents c writes.
+func mediaType(c Codec) string {
+	if m, ok := c.(interface{ MediaType() string }); ok {
+		return m.MediaType()
+	}
+	return "application/json"
+}
+
+// Built-in codecs.  Map keys of other types than strings are read as their
+// string form, so the integer key 1 becomes "1".
+var (
+	JSON        Codec = jsonCodec{}
+	CBOR        Codec = cborCodec{}
+	MessagePack Codec = msgpackCodec{}
+)
+
+// maxNesting limits the depth of arrays and maps read by the binary codecs.
+const maxNesting = 10000
+
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(b []byte) (interface{}, error) {
+	if len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (jsonCodec) MediaType() string { return "application/json" }
+
+// Encode writes binary strings in base64, like encoding/json.
+func (jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// mapKey returns the object member name for a decoded map key.
+func mapKey(k interface{}) (string, error) {
+	switch x := k.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case nil:
+		return "null", nil
+	}
+	return "", fmt.Errorf("rfc6902: unsupported map key of type %T", k)
+}
+
+// fromUint, fromInt and fromBig return an integer read by the binary codecs
+// as a float64, or an error when a float64 cannot hold it exactly.
+func fromUint(u uint64) (interface{}, error) {
+	if u <= 1<<53 {
+		return float64(u), nil
+	}
+	return fromBig(new(big.Int).SetUint64(u))
+}
+
+func fromInt(i int64) (interface{}, error) {
+	if i >= -1<<53 && i <= 1<<53 {
+		return float64(i), nil
+	}
+	return fromBig(big.NewInt(i))
+}
+
+func fromBig(i *big.Int) (interface{}, error) {
+	f, acc := new(big.Float).SetInt(i).Float64()
+	if acc != big.Exact {
+		return nil, fmt.Errorf("rfc6902: integer %s cannot be read exactly", i)
+	}
+	return f, nil
+}
+
+// integral reports whether f is written as an integer by the binary codecs.
+func integral(f float64) bool {
+	return f == math.Trunc(f) && math.Abs(f) < 1<<63
+}
+
+// sameValue is reflect.DeepEqual, except that a binary string equals the
+// string holding the same bytes.
+func sameValue(a, b interface{}) bool {
+	switch x := a.(type) {
+	case []byte:
+		if y, ok := b.(string); ok {
+			return string(x) == y
+		}
+	case string:
+		if y, ok := b.([]byte); ok {
+			return x == string(y)
+		}
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !sameValue(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !sameValue(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
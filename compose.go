@@ -0,0 +1,248 @@
+package rfc6902
+
+import (
+	"strconv"
+)
+
+// Compose returns a single patch that has the same effect as applying p1
+// followed by p2. The combined operations are squashed (see Squash).
+func Compose(p1, p2 *Patcher) *Patcher {
+	ops := make([]Operation, 0, len(p1.ops)+len(p2.ops))
+	ops = append(ops, p1.ops...)
+	ops = append(ops, p2.ops...)
+	return (&Patcher{ops: ops}).Squash()
+}
+
+/*
+Squash returns a new patch where consecutive operations on the same location
+are merged.  The receiver is left untouched.
+
+Operations are only merged when no operation in between can observe or shift
+the location, and only when the result is identical to sequential application
+for every document the original operations apply to:
+
+	add/replace X, replace X       => add/replace X with the later value
+	replace X, remove X            => remove X
+	add/replace X, op under X      => add/replace X with the op applied to the value
+	remove X, add X, remove X      => remove X
+	move A->X, move X->B           => move A->B (X known to be vacant)
+	add X, move X->B               => add B (X known to be vacant)
+	move A->X, remove X            => remove A (X known to be vacant)
+
+An add followed by a remove of the same object member is only cancelled when
+the member is known not to have existed beforehand, since otherwise the pair
+removes the previous value.
+*/
+func (p *Patcher) Squash() *Patcher {
+	out := make([]Operation, 0, len(p.ops))
+	for _, o := range p.ops {
+		o.Value = clone(o.Value)
+		out = squash(out, o)
+	}
+	return &Patcher{ops: out}
+}
+
+// squash appends b to the already squashed ops, merging it with the last
+// operation it depends on where possible.
+func squash(ops []Operation, b Operation) []Operation {
+	i := lastConflict(ops, b)
+	if i < 0 {
+		return append(ops, b)
+	}
+	a := ops[i]
+	merged, ok := combine(ops[:i], a, b)
+	if !ok {
+		return append(ops, b)
+	}
+	rest := append(merged, ops[i+1:]...)
+	return append(ops[:i], rest...)
+}
+
+// combine merges b into a, where a is the last operation in prior+a that b
+// depends on.  The replacement for a is returned; b is always consumed when
+// ok is true.
+func combine(prior []Operation, a, b Operation) (merged []Operation, ok bool) {
+	switch {
+	case b.Op == "replace" && b.Path == a.Path && (a.Op == "add" || a.Op == "replace"):
+		a.Value = b.Value
+		return []Operation{a}, true
+	case b.Op == "remove" && b.Path == a.Path && a.Op == "replace":
+		return []Operation{b}, true
+	case b.Op == "remove" && b.Path == a.Path && a.Op == "add":
+		if vacated(prior, a.Path) {
+			return nil, true
+		}
+	case b.Op == "remove" && b.Path == a.Path && a.Op == "move":
+		if vacated(prior, a.Path) {
+			return []Operation{{Op: "remove", Path: a.From}}, true
+		}
+	case b.Op == "move" && b.From == a.Path && a.Op == "move":
+		if vacated(prior, a.Path) {
+			if a.From == b.Path {
+				return nil, true
+			}
+			return []Operation{{Op: "move", Path: b.Path, From: a.From}}, true
+		}
+	case b.Op == "move" && b.From == a.Path && a.Op == "add":
+		if vacated(prior, a.Path) {
+			return []Operation{{Op: "add", Path: b.Path, Value: a.Value}}, true
+		}
+	case a.Op == "add" || a.Op == "replace":
+		if v, ok := fold(a, b); ok {
+			a.Value = v
+			return []Operation{a}, true
+		}
+	}
+	return nil, false
+}
+
+// fold applies b to the value written by a when every location b touches
+// lies strictly below a's path.
+func fold(a, b Operation) (interface{}, bool) {
+	base, err := newJSONPointer(a.Path)
+	if err != nil {
+		return nil, false
+	}
+	locs := b.locations()
+	for _, loc := range locs {
+		if !below(loc, base) {
+			return nil, false
+		}
+	}
+	if locs == nil {
+		return nil, false
+	}
+	rel := b
+	rel.Path = locs[0][len(base):].path()
+	if len(locs) > 1 {
+		rel.From = locs[1][len(base):].path()
+	}
+	v, err := rel.apply(clone(a.Value))
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// vacated reports whether the last operation in ops touching path leaves
+// nothing at path.
+func vacated(ops []Operation, path string) bool {
+	ptr, err := newJSONPointer(path)
+	if err != nil {
+		return false
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		if !ops[i].touches(ptr) {
+			continue
+		}
+		o := ops[i]
+		return (o.Op == "remove" && o.Path == path) ||
+			(o.Op == "move" && o.From == path && o.Path != path)
+	}
+	return false
+}
+
+// lastConflict returns the index of the last op that b cannot be reordered
+// with, or -1.
+func lastConflict(ops []Operation, b Operation) int {
+	locs := b.locations()
+	if locs == nil {
+		return len(ops) - 1
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		for _, loc := range locs {
+			if ops[i].touches(loc) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// locations returns the parsed path and from pointers of the op.  nil is
+// returned when a pointer cannot be parsed.
+func (o *Operation) locations() []jsonptr {
+	path, err := newJSONPointer(o.Path)
+	if err != nil {
+		return nil
+	}
+	locs := []jsonptr{path}
+	if o.Op == "move" || o.Op == "copy" {
+		from, err := newJSONPointer(o.From)
+		if err != nil {
+			return nil
+		}
+		locs = append(locs, from)
+	}
+	return locs
+}
+
+// touches reports whether the op reads, writes or shifts ptr.
+func (o *Operation) touches(ptr jsonptr) bool {
+	locs := o.locations()
+	if locs == nil {
+		return true
+	}
+	for _, loc := range locs {
+		if overlaps(loc, ptr) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether operations on x and y may interfere: either is
+// an ancestor of the other or one of them may shift the other inside an
+// array.
+func overlaps(x, y jsonptr) bool {
+	n := commonPrefix(x, y)
+	if n == len(x) || n == len(y) {
+		return true
+	}
+	if n == len(x)-1 || n == len(y)-1 {
+		return isIndex(x[n]) || isIndex(y[n])
+	}
+	return false
+}
+
+// below reports whether ptr lies strictly below base.
+func below(ptr, base jsonptr) bool {
+	return len(ptr) > len(base) && commonPrefix(ptr, base) == len(base)
+}
+
+func commonPrefix(x, y jsonptr) int {
+	n := 0
+	for n < len(x) && n < len(y) && x[n].token() == y[n].token() {
+		n++
+	}
+	return n
+}
+
+// isIndex reports whether the token could address an array element.
+func isIndex(r reftoken) bool {
+	if r == "-" {
+		return true
+	}
+	_, err := strconv.Atoi(string(r))
+	return err == nil
+}
+
+// clone returns a deep copy of a decoded JSON value.
+func clone(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = clone(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = clone(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
@@ -0,0 +1,111 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_Squash(t *testing.T) {
+	tests := []struct {
+		patch, expected string
+		docs            []string
+	}{
+		{
+			patch:    `[{"op": "add", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`,
+			expected: `[{"op": "add", "path": "/a", "value": 2}]`,
+			docs:     []string{`{}`, `{"a": 0}`},
+		},
+		{
+			patch:    `[{"op": "replace", "path": "/a", "value": 1}, {"op": "replace", "path": "/b", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`,
+			expected: `[{"op": "replace", "path": "/a", "value": 2}, {"op": "replace", "path": "/b", "value": 1}]`,
+			docs:     []string{`{"a": 0, "b": 0}`},
+		},
+		{
+			patch:    `[{"op": "replace", "path": "/a", "value": 1}, {"op": "remove", "path": "/a"}]`,
+			expected: `[{"op": "remove", "path": "/a"}]`,
+			docs:     []string{`{"a": 0}`},
+		},
+		{
+			patch:    `[{"op": "add", "path": "/a", "value": {}}, {"op": "add", "path": "/a/b", "value": [1]}, {"op": "add", "path": "/a/b/-", "value": 2}, {"op": "remove", "path": "/a/b/0"}]`,
+			expected: `[{"op": "add", "path": "/a", "value": {"b": [2]}}]`,
+			docs:     []string{`{}`, `{"a": 1}`},
+		},
+		{
+			patch:    `[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/a"}]`,
+			expected: `[{"op": "remove", "path": "/a"}]`,
+			docs:     []string{`{"a": 0}`},
+		},
+		{
+			// /a may have existed before, so the pair is not a no-op.
+			patch:    `[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/a"}]`,
+			expected: `[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/a"}]`,
+			docs:     []string{`{}`, `{"a": 0}`},
+		},
+		{
+			patch:    `[{"op": "remove", "path": "/b"}, {"op": "move", "from": "/a", "path": "/b"}, {"op": "move", "from": "/b", "path": "/c"}]`,
+			expected: `[{"op": "remove", "path": "/b"}, {"op": "move", "from": "/a", "path": "/c"}]`,
+			docs:     []string{`{"a": 1, "b": 2}`},
+		},
+		{
+			patch:    `[{"op": "remove", "path": "/l/1"}, {"op": "move", "from": "/l/0", "path": "/l/1"}, {"op": "move", "from": "/l/1", "path": "/l/0"}]`,
+			expected: `[{"op": "remove", "path": "/l/1"}]`,
+			docs:     []string{`{"l": [1, 2, 3]}`},
+		},
+		{
+			// the test observes the intermediate value
+			patch:    `[{"op": "replace", "path": "/a", "value": 1}, {"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`,
+			expected: `[{"op": "replace", "path": "/a", "value": 1}, {"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`,
+			docs:     []string{`{"a": 0}`},
+		},
+		{
+			// removing /l/0 shifts /l/1
+			patch:    `[{"op": "replace", "path": "/l/1", "value": 1}, {"op": "remove", "path": "/l/0"}, {"op": "replace", "path": "/l/1", "value": 2}]`,
+			expected: `[{"op": "replace", "path": "/l/1", "value": 1}, {"op": "remove", "path": "/l/0"}, {"op": "replace", "path": "/l/1", "value": 2}]`,
+			docs:     []string{`{"l": [0, 0, 0]}`},
+		},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Failed parsing: %q. %s", i, test.patch, err)
+		}
+		squashed := p.Squash()
+		actual, _ := json.Marshal(squashed)
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: Squash() (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+		for _, doc := range test.docs {
+			want, err := p.Apply([]byte(doc))
+			if err != nil {
+				t.Fatalf("%d: original patch failed on %s: %s", i, doc, err)
+			}
+			got, err := squashed.Apply([]byte(doc))
+			if err != nil {
+				t.Fatalf("%d: squashed patch failed on %s: %s", i, doc, err)
+			}
+			if !jsonEqual(got, want) {
+				t.Errorf("%d: on %s (actual) %s != %s (expected)", i, doc, got, want)
+			}
+		}
+	}
+}
+
+func Test_Compose(t *testing.T) {
+	p1, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/tags", "value": ["a"]}]`))
+	p2, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/tags/-", "value": "b"}, {"op": "replace", "path": "/name", "value": "x"}]`))
+
+	c := Compose(p1, p2)
+	actual, _ := json.Marshal(c)
+	expected := `[{"op": "add", "path": "/tags", "value": ["a", "b"]}, {"op": "replace", "path": "/name", "value": "x"}]`
+	if !jsonEqual(actual, []byte(expected)) {
+		t.Errorf("Compose() (actual) %s != %s (expected)", actual, expected)
+	}
+	if len(p1.ops) != 1 || len(p2.ops) != 2 {
+		t.Errorf("Compose() modified its arguments")
+	}
+	if v := p1.ops[0].Value.([]interface{}); len(v) != 1 {
+		t.Errorf("Compose() modified the value of p1: %v", v)
+	}
+}
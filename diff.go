@@ -0,0 +1,151 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// CreatePatch returns a patch that transforms the JSON document a into b.
+func CreatePatch(a, b []byte) (*Patcher, error) {
+	if len(a) <= 0 || len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return nil, err
+	}
+	return &Patcher{ops: diff(jsonptr{}, av, bv)}, nil
+}
+
+/*
+diff walks a and b in parallel and returns the operations transforming a at
+path into b.
+
+Object members are visited in key order.  Arrays are aligned on their longest
+common subsequence; an element removed where another one is inserted is
+diffed in place instead.  Array edits are ordered from the last index to the
+first, so every generated index refers to a position in a.  Values of
+different types are replaced.
+*/
+func diff(path jsonptr, a, b interface{}) []Operation {
+	return diffWith(path, a, b, nil)
+}
+
+// diffWith is diff, matching the elements of arrays configured in keys by
+// their key (see CreateKeyedPatch).
+func diffWith(path jsonptr, a, b interface{}, keys Keys) []Operation {
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	switch at := a.(type) {
+	case map[string]interface{}:
+		if bt, ok := b.(map[string]interface{}); ok {
+			return diffObject(path, at, bt, keys)
+		}
+	case []interface{}:
+		if bt, ok := b.([]interface{}); ok {
+			if m := keys.member(path); m != "" {
+				if ops, ok := diffKeyed(path, at, bt, m, keys); ok {
+					return ops
+				}
+			}
+			return diffArray(path, at, bt, keys)
+		}
+	}
+	return []Operation{{Op: "replace", Path: path.path(), Value: clone(b)}}
+}
+
+func diffObject(path jsonptr, a, b map[string]interface{}, keys Keys) (ops []Operation) {
+	for _, k := range sortedKeys(a) {
+		child := path.child(reftoken(encode(k)))
+		if bv, ok := b[k]; ok {
+			ops = append(ops, diffWith(child, a[k], bv, keys)...)
+		} else {
+			ops = append(ops, Operation{Op: "remove", Path: child.path()})
+		}
+	}
+	for _, k := range sortedKeys(b) {
+		if _, ok := a[k]; !ok {
+			child := path.child(reftoken(encode(k)))
+			ops = append(ops, Operation{Op: "add", Path: child.path(), Value: clone(b[k])})
+		}
+	}
+	return
+}
+
+func diffArray(path jsonptr, a, b []interface{}, keys Keys) (ops []Operation) {
+	// lcs[i][j] is the length of the common subsequence of a[:i] and b[:j]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if reflect.DeepEqual(a[i-1], b[j-1]) {
+				lcs[i][j] = lcs[i-1][j-1] + 1
+			} else if lcs[i-1][j] >= lcs[i][j-1] {
+				lcs[i][j] = lcs[i-1][j]
+			} else {
+				lcs[i][j] = lcs[i][j-1]
+			}
+		}
+	}
+
+	// Edits are emitted from the end of the array backwards so every index
+	// refers to a position in a.
+	i, j := len(a), len(b)
+	for i > 0 || j > 0 {
+		if i > 0 && j > 0 && reflect.DeepEqual(a[i-1], b[j-1]) {
+			i, j = i-1, j-1
+			continue
+		}
+		// collect the run of removals and insertions back to the previous match
+		di, dj := i, j
+		for di > 0 || dj > 0 {
+			if di > 0 && dj > 0 && reflect.DeepEqual(a[di-1], b[dj-1]) {
+				break
+			}
+			if dj == 0 || (di > 0 && lcs[di-1][dj] >= lcs[di][dj-1]) {
+				di--
+			} else {
+				dj--
+			}
+		}
+		pairs := i - di
+		if j-dj < pairs {
+			pairs = j - dj
+		}
+		for ; j-dj > pairs; j-- {
+			child := path.child(indexToken(di + pairs))
+			ops = append(ops, Operation{Op: "add", Path: child.path(), Value: clone(b[j-1])})
+		}
+		for ; i-di > pairs; i-- {
+			child := path.child(indexToken(i - 1))
+			ops = append(ops, Operation{Op: "remove", Path: child.path()})
+		}
+		for ; i > di; i, j = i-1, j-1 {
+			child := path.child(indexToken(i - 1))
+			ops = append(ops, diffWith(child, a[i-1], b[j-1], keys)...)
+		}
+	}
+	return
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func indexToken(i int) reftoken {
+	return reftoken(strconv.Itoa(i))
+}
@@ -0,0 +1,58 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_CreatePatch(t *testing.T) {
+	tests := []struct {
+		a, b, expected string
+	}{
+		{`{"a": 1}`, `{"a": 1}`, `[]`},
+		{`{"a": 1, "b": 2}`, `{"a": 3, "c": 4}`, `[{"op": "replace", "path": "/a", "value": 3}, {"op": "remove", "path": "/b"}, {"op": "add", "path": "/c", "value": 4}]`},
+		{`{"a/b": {"c~d": 1}}`, `{"a/b": {"c~d": 2}}`, `[{"op": "replace", "path": "/a~1b/c~0d", "value": 2}]`},
+		{`{"l": [1, 2, 3]}`, `{"l": [1, 3]}`, `[{"op": "remove", "path": "/l/1"}]`},
+		{`{"l": [1, 2, 3]}`, `{"l": [0, 1, 2, 3, 4]}`, `[{"op": "add", "path": "/l/3", "value": 4}, {"op": "add", "path": "/l/0", "value": 0}]`},
+		{`{"l": [{"id": 1, "n": "a"}, 2]}`, `{"l": [{"id": 1, "n": "b"}, 2]}`, `[{"op": "replace", "path": "/l/0/n", "value": "b"}]`},
+		{`{"l": [1, 2, 3]}`, `{"l": [1, "x", "y", 3]}`, `[{"op": "add", "path": "/l/2", "value": "y"}, {"op": "replace", "path": "/l/1", "value": "x"}]`},
+		{`{"a": [1]}`, `{"a": {"b": 1}}`, `[{"op": "replace", "path": "/a", "value": {"b": 1}}]`},
+	}
+
+	for i, test := range tests {
+		p, err := CreatePatch([]byte(test.a), []byte(test.b))
+		if err != nil {
+			t.Fatalf("%d: CreatePatch failed: %s", i, err)
+		}
+		actual, _ := json.Marshal(p)
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: CreatePatch() (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+}
+
+func Test_CreatePatch_RoundTrip(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{`{"a": [1, 2, 3, 4, 5], "b": {"c": [true, false]}}`, `{"a": [5, 4, 3, 2, 1], "b": {"c": [false], "d": null}}`},
+		{`[[1, 2], [3, 4], "x"]`, `[[1], [3, 4, 5], "y", "x", []]`},
+		{`["a", "b", "c", "d"]`, `["e", "b", "f", "g", "d", "h"]`},
+		{`{"x": 1}`, `[1]`},
+		{largedoc, `[]`},
+	}
+
+	for i, test := range tests {
+		p, err := CreatePatch([]byte(test.a), []byte(test.b))
+		if err != nil {
+			t.Fatalf("%d: CreatePatch failed: %s", i, err)
+		}
+		actual, err := p.Apply([]byte(test.a))
+		if err != nil {
+			t.Fatalf("%d: Unable to apply created patch: %s", i, err)
+		}
+		if !jsonEqual(actual, []byte(test.b)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.b)
+		}
+	}
+}
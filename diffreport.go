@@ -0,0 +1,221 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ChangeKind classifies a Change.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeChanged
+	ChangeMoved
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeChanged:
+		return "changed"
+	}
+	return "moved"
+}
+
+// MarshalText encodes the kind as "added", "removed", "changed" or "moved".
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+/*
+Change is a node that differs between two documents.  Path points to it, and
+From to where a moved node came from.  Old is the value removed or replaced,
+New the value added, the replacement or the value moved.
+
+Like the operations of a patch, each change is relative to the document as
+the changes before it left it.
+*/
+type Change struct {
+	Kind ChangeKind  `json:"kind"`
+	Path string      `json:"path"`
+	From string      `json:"from,omitempty"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// DiffReport lists the changes between two documents, one per operation of
+// the patch CreatePatch returns for them.
+type DiffReport struct {
+	Changes []Change `json:"changes"`
+	ops     []Operation
+}
+
+// Compare returns the structural differences between the JSON documents a
+// and b.
+func Compare(a, b []byte) (*DiffReport, error) {
+	return CompareKeyed(a, b, nil)
+}
+
+// CompareKeyed is Compare, matching the elements of the arrays configured in
+// keys by their key like CreateKeyedPatch.  Reordered elements are reported
+// as moved.
+func CompareKeyed(a, b []byte, keys Keys) (*DiffReport, error) {
+	if len(a) <= 0 || len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return nil, err
+	}
+
+	ops := diffWith(jsonptr{}, av, bv, keys)
+	r := &DiffReport{Changes: make([]Change, 0, len(ops)), ops: ops}
+	doc := av
+	for _, op := range ops {
+		concrete, err := keys.resolveOp(op, doc)
+		if err != nil {
+			return nil, err
+		}
+		ptr, err := newJSONPointer(concrete.Path)
+		if err != nil {
+			return nil, err
+		}
+		c := Change{Path: op.Path, From: op.From}
+		switch op.Op {
+		case "add":
+			c.Kind = ChangeAdded
+		case "remove":
+			c.Kind, c.Old = ChangeRemoved, lookup(ptr, doc)
+		case "replace":
+			c.Kind, c.Old = ChangeChanged, lookup(ptr, doc)
+		case "move":
+			c.Kind = ChangeMoved
+		}
+		if doc, err = concrete.apply(doc); err != nil {
+			return nil, err
+		}
+		if op.Op != "remove" {
+			c.New = lookup(ptr, doc)
+		}
+		r.Changes = append(r.Changes, c)
+	}
+	return r, nil
+}
+
+// Patch returns the patch making the changes of the report.  A patch of a
+// keyed report must be applied with ApplyOptions.Keys set.
+func (r *DiffReport) Patch() *Patcher {
+	return &Patcher{ops: r.ops}
+}
+
+/*
+Render writes the report in the format f:
+
+	FormatPlain     a line per change: "+" added, "-" removed, "~" changed
+	                and ">" moved
+	FormatANSI      the lines of FormatPlain colored
+	FormatMarkdown  a table with the kind, path, old and new value of every
+	                change
+	FormatHTML      the same table in HTML, each row of the class of its kind
+	FormatJSON      the report encoded as JSON
+*/
+func (r *DiffReport) Render(f Format) (string, error) {
+	switch f {
+	case FormatPlain, FormatANSI:
+		var b strings.Builder
+		for _, c := range r.Changes {
+			var line, color string
+			switch c.Kind {
+			case ChangeAdded:
+				line, color = "+ "+c.Path+": "+display(c.New, ""), ansiGreen
+			case ChangeRemoved:
+				line, color = "- "+c.Path+": "+display(c.Old, ""), ansiRed
+			case ChangeChanged:
+				line, color = "~ "+c.Path+": "+display(c.Old, "")+" -> "+display(c.New, ""), ansiYellow
+			case ChangeMoved:
+				line, color = "> "+c.From+" -> "+c.Path+": "+display(c.New, ""), ansiCyan
+			}
+			if f == FormatANSI {
+				line = color + line + ansiReset
+			}
+			b.WriteString(line + "\n")
+		}
+		return b.String(), nil
+
+	case FormatMarkdown:
+		var b strings.Builder
+		b.WriteString("| Change | Path | Old | New |\n|---|---|---|---|\n")
+		for _, c := range r.Changes {
+			path := mdCode(c.Path)
+			if c.Kind == ChangeMoved {
+				path = mdCode(c.From) + " → " + path
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", c.Kind, path, mdValue(c, true), mdValue(c, false))
+		}
+		return b.String(), nil
+
+	case FormatHTML:
+		var b strings.Builder
+		b.WriteString("<table class=\"rfc6902-diff\">\n<thead><tr><th>Change</th><th>Path</th><th>Old</th><th>New</th></tr></thead>\n<tbody>\n")
+		for _, c := range r.Changes {
+			path := "<code>" + html.EscapeString(c.Path) + "</code>"
+			if c.Kind == ChangeMoved {
+				path = "<code>" + html.EscapeString(c.From) + "</code> → " + path
+			}
+			fmt.Fprintf(&b, "<tr class=\"%s\"><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+				c.Kind, c.Kind, path, htmlValue(c, true), htmlValue(c, false))
+		}
+		b.WriteString("</tbody>\n</table>\n")
+		return b.String(), nil
+
+	case FormatJSON:
+		b, err := json.Marshal(r)
+		return string(b), err
+	}
+	return "", fmt.Errorf("rfc6902: unsupported format %d", f)
+}
+
+// shows reports whether the old or new column of c holds a value.
+func shows(c Change, old bool) bool {
+	if old {
+		return c.Kind == ChangeRemoved || c.Kind == ChangeChanged
+	}
+	return c.Kind != ChangeRemoved
+}
+
+func mdValue(c Change, old bool) string {
+	if !shows(c, old) {
+		return ""
+	}
+	if old {
+		return mdCode(display(c.Old, ""))
+	}
+	return mdCode(display(c.New, ""))
+}
+
+// mdCode writes s as a code span in a Markdown table cell.
+func mdCode(s string) string {
+	return "`" + strings.ReplaceAll(s, "|", "\\|") + "`"
+}
+
+func htmlValue(c Change, old bool) string {
+	if !shows(c, old) {
+		return ""
+	}
+	v := c.New
+	if old {
+		v = c.Old
+	}
+	return "<code>" + html.EscapeString(display(v, "")) + "</code>"
+}
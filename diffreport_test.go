@@ -0,0 +1,92 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_Compare(t *testing.T) {
+	a := `{"a": 1, "b": [1, 2], "c": {"d": null}, "e": "x"}`
+	b := `{"a": 2, "b": [1], "c": {"d": null, "f": "<&>"}}`
+	r, err := Compare([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatalf("Compare failed: %s", err)
+	}
+	expected := `{"changes": [
+		{"kind": "changed", "path": "/a", "old": 1, "new": 2},
+		{"kind": "removed", "path": "/b/1", "old": 2, "new": null},
+		{"kind": "added", "path": "/c/f", "old": null, "new": "<&>"},
+		{"kind": "removed", "path": "/e", "old": "x", "new": null}
+	]}`
+	actual, err := r.Render(FormatJSON)
+	if err != nil {
+		t.Fatalf("Render failed: %s", err)
+	}
+	if !jsonEqual([]byte(actual), []byte(expected)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, expected)
+	}
+
+	// the report agrees with CreatePatch
+	p, _ := CreatePatch([]byte(a), []byte(b))
+	pj, _ := p.MarshalJSON()
+	rj, _ := r.Patch().MarshalJSON()
+	if string(pj) != string(rj) {
+		t.Errorf("(actual) %s != %s (expected)", rj, pj)
+	}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{FormatPlain, "~ /a: 1 -> 2\n- /b/1: 2\n+ /c/f: \"<&>\"\n- /e: \"x\"\n"},
+		{FormatANSI, "\x1b[33m~ /a: 1 -> 2\x1b[0m\n\x1b[31m- /b/1: 2\x1b[0m\n\x1b[32m+ /c/f: \"<&>\"\x1b[0m\n\x1b[31m- /e: \"x\"\x1b[0m\n"},
+		{FormatMarkdown, "| Change | Path | Old | New |\n|---|---|---|---|\n" +
+			"| changed | `/a` | `1` | `2` |\n" +
+			"| removed | `/b/1` | `2` |  |\n" +
+			"| added | `/c/f` |  | `\"<&>\"` |\n" +
+			"| removed | `/e` | `\"x\"` |  |\n"},
+		{FormatHTML, "<table class=\"rfc6902-diff\">\n<thead><tr><th>Change</th><th>Path</th><th>Old</th><th>New</th></tr></thead>\n<tbody>\n" +
+			"<tr class=\"changed\"><td>changed</td><td><code>/a</code></td><td><code>1</code></td><td><code>2</code></td></tr>\n" +
+			"<tr class=\"removed\"><td>removed</td><td><code>/b/1</code></td><td><code>2</code></td><td></td></tr>\n" +
+			"<tr class=\"added\"><td>added</td><td><code>/c/f</code></td><td></td><td><code>&#34;&lt;&amp;&gt;&#34;</code></td></tr>\n" +
+			"<tr class=\"removed\"><td>removed</td><td><code>/e</code></td><td><code>&#34;x&#34;</code></td><td></td></tr>\n" +
+			"</tbody>\n</table>\n"},
+	}
+	for i, test := range tests {
+		actual, err := r.Render(test.format)
+		if err != nil {
+			t.Errorf("%d: Render failed: %s", i, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, actual, test.expected)
+		}
+	}
+	if _, err := r.Render(Format(-1)); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func Test_CompareKeyed(t *testing.T) {
+	a := `{"items": [{"id": 1}, {"id": 2, "v": "a|b"}]}`
+	b := `{"items": [{"id": 2, "v": "a|b"}, {"id": 1}]}`
+	keys := Keys{"/items": "id"}
+	r, err := CompareKeyed([]byte(a), []byte(b), keys)
+	if err != nil {
+		t.Fatalf("CompareKeyed failed: %s", err)
+	}
+	if len(r.Changes) != 1 || r.Changes[0].Kind != ChangeMoved || r.Changes[0].From != "/items/[id=2]" {
+		b, _ := json.Marshal(r)
+		t.Fatalf("unexpected report %s", b)
+	}
+	md, _ := r.Render(FormatMarkdown)
+	if !strings.Contains(md, "| moved | `/items/[id=2]` → `/items/0` |  | `{\"id\":2,\"v\":\"a\\|b\"}` |") {
+		t.Errorf("unexpected table %q", md)
+	}
+
+	actual, err := r.Patch().ApplyWithOptions([]byte(a), &ApplyOptions{Keys: keys})
+	if err != nil || !jsonEqual(actual, []byte(b)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, b)
+	}
+}
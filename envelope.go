@@ -0,0 +1,112 @@
+package rfc6902
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrorVersionConflict is returned when an envelope is applied to a
+	// document other than its base.
+	ErrorVersionConflict = errors.New("rfc6902: document is not the base version of the patch")
+	// ErrorHashMismatch is returned when applying an envelope does not
+	// produce the document it records.
+	ErrorHashMismatch = errors.New("rfc6902: patched document does not match the envelope")
+)
+
+/*
+An Envelope records a patch applied to a version of a document, as an event.
+BaseHash and Hash identify the documents before and after the patch by
+content, see Hash, so an envelope only applies to the document it was made
+for.
+*/
+type Envelope struct {
+	DocumentID  string    `json:"document_id"`
+	BaseVersion int       `json:"base_version"`
+	Version     int       `json:"version"`
+	BaseHash    string    `json:"base_hash"`
+	Hash        string    `json:"hash"`
+	Timestamp   time.Time `json:"timestamp"`
+	Actor       string    `json:"actor,omitempty"`
+	Patch       *Patcher  `json:"patch"`
+}
+
+// NewEnvelope applies p to base, the version baseVersion of the document id,
+// and returns the envelope recording it by actor, with the patched document.
+func NewEnvelope(id string, baseVersion int, base []byte, p *Patcher, actor string) (*Envelope, []byte, error) {
+	result, err := p.Apply(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &Envelope{
+		DocumentID:  id,
+		BaseVersion: baseVersion,
+		Version:     baseVersion + 1,
+		Timestamp:   time.Now().UTC(),
+		Actor:       actor,
+		Patch:       p,
+	}
+	if e.BaseHash, err = Hash(base); err != nil {
+		return nil, nil, err
+	}
+	if e.Hash, err = Hash(result); err != nil {
+		return nil, nil, err
+	}
+	return e, result, nil
+}
+
+// Apply applies the patch of the envelope to doc.  It fails with
+// ErrorVersionConflict if doc is not the base of the envelope, and with
+// ErrorHashMismatch if the result is not the one recorded.
+func (e *Envelope) Apply(doc []byte) ([]byte, error) {
+	h, err := Hash(doc)
+	if err != nil {
+		return nil, err
+	}
+	if h != e.BaseHash {
+		return nil, ErrorVersionConflict
+	}
+	result, err := e.Patch.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+	if h, err = Hash(result); err != nil {
+		return nil, err
+	}
+	if h != e.Hash {
+		return nil, ErrorHashMismatch
+	}
+	return result, nil
+}
+
+// ParseEnvelope reads an envelope encoded as JSON from r.
+func ParseEnvelope(r io.Reader) (*Envelope, error) {
+	return new(Parser).ParseEnvelope(r)
+}
+
+// ParseEnvelope reads an envelope encoded as JSON from r, parsing its patch
+// with ps.
+func (ps *Parser) ParseEnvelope(r io.Reader) (*Envelope, error) {
+	if r == nil {
+		return nil, errors.New("reader is nil")
+	}
+	var in struct {
+		Envelope
+		Patch json.RawMessage `json:"patch"`
+	}
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+	if in.Patch == nil {
+		return nil, errors.New("rfc6902: envelope without patch")
+	}
+	e := in.Envelope
+	var err error
+	if e.Patch, err = ps.Parse(bytes.NewReader(in.Patch)); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,59 @@
+package rfc6902
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_Envelope(t *testing.T) {
+	base := []byte(`{"a": 1, "b": [1]}`)
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/b/-", "value": 2}]`))
+	e, result, err := NewEnvelope("doc-1", 3, base, p, "alice")
+	if err != nil {
+		t.Fatalf("NewEnvelope failed: %s", err)
+	}
+	if e.Version != 4 || e.DocumentID != "doc-1" || e.Actor != "alice" || e.Timestamp.IsZero() {
+		t.Errorf("bad envelope %+v", e)
+	}
+	if !jsonEqual(result, []byte(`{"a": 1, "b": [1, 2]}`)) {
+		t.Errorf("(actual) %s != %s (expected)", result, `{"a": 1, "b": [1, 2]}`)
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	e, err = ParseEnvelope(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ParseEnvelope failed: %s", err)
+	}
+
+	// member order and whitespace do not change the hash
+	actual, err := e.Apply([]byte(`{"b":[1],"a":1}`))
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if !jsonEqual(actual, result) {
+		t.Errorf("(actual) %s != %s (expected)", actual, result)
+	}
+	if _, err = e.Apply(result); err != ErrorVersionConflict {
+		t.Errorf("expected ErrorVersionConflict, got %v", err)
+	}
+
+	e.Hash = "sha256:00"
+	if _, err = e.Apply(base); err != ErrorHashMismatch {
+		t.Errorf("expected ErrorHashMismatch, got %v", err)
+	}
+
+	for i, in := range []string{
+		`{"document_id": "x"}`,
+		`{"patch": [{"op": "bogus"}]}`,
+		`{"patch": `,
+	} {
+		if _, err = ParseEnvelope(strings.NewReader(in)); err == nil {
+			t.Errorf("%d: expected an error for %s", i, in)
+		}
+	}
+}
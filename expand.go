@@ -0,0 +1,267 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// step kinds of a wildcard target
+const (
+	childStep   = iota // the named member or element
+	anyStep            // every member or element
+	descendStep        // the value itself and everything below it
+)
+
+type step struct {
+	kind  int
+	token reftoken
+}
+
+// Expand returns the patch with every wildcard path replaced by the operations
+// it stands for in the JSON document b.  This is the patch ApplyOptions.Wildcards
+// applies.
+//
+// A wildcard path is either a JSON Pointer containing the reference token "*",
+// matching every member or element, or "**", matching any number of levels; or
+// a JSONPath expression starting with "$", supporting the .name, ['name'],
+// [index], .*, [*] and .. selectors.  In JSONPath ['*'] names the member "*".
+//
+// Each operation is expanded against the document as the operations before it
+// left it, into one operation per match.  Matches are emitted last first, so
+// removing several elements of an array removes the right ones.  Every match
+// must exist, except the final member of an add, move or copy destination that does
+// not follow a "**" or "..", so {"op": "add", "path": "/*/active", "value":
+// true} sets active in every object of the array.  Wildcards are only
+// supported in path, not in from.
+func (p *Patcher) Expand(b []byte) (*Patcher, error) {
+	if len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return p.expand(v, &ApplyOptions{Wildcards: true})
+}
+
+// expand returns the operations of the patch as they apply to v, with keyed
+// tokens resolved to indices when o.Keys is not nil and wildcard paths
+// replaced by their matches when o.Wildcards is set.  Keys are resolved
+// against the document as the operations before left it, both before a path
+// is expanded and in each of its matches.  o.Limits bounds the bytes copied
+// and the document after every operation, so a patch cannot exhaust memory
+// before the expanded patch is checked.
+func (p *Patcher) expand(v interface{}, o *ApplyOptions) (*Patcher, error) {
+	keys, limits := o.Keys, o.Limits
+	doc := clone(v)
+	ops := make([]Operation, 0, len(p.ops))
+	copied := 0
+	for i, op := range p.ops {
+		var err error
+		if keys != nil && !strings.HasPrefix(op.Path, "$") {
+			if op, err = keys.resolveOp(op, doc); err != nil {
+				return nil, err
+			}
+		}
+		var steps []step
+		if o.Wildcards {
+			if steps, err = wildcards(op.From); err != nil {
+				return nil, err
+			}
+			if steps != nil {
+				return nil, fmt.Errorf("rfc6902: wildcard from at %d", i)
+			}
+			if steps, err = wildcards(op.Path); err != nil {
+				return nil, err
+			}
+		}
+
+		concrete := []Operation{op}
+		if steps != nil {
+			var matches []jsonptr
+			strict := op.Op != "add" && op.Op != "move" && op.Op != "copy"
+			match(steps, jsonptr{}, doc, strict, &matches)
+			concrete = make([]Operation, 0, len(matches))
+			for j := len(matches) - 1; j >= 0; j-- {
+				c := op
+				c.Path = matches[j].path()
+				concrete = append(concrete, c)
+			}
+		}
+		for j, c := range concrete {
+			if keys != nil && steps != nil {
+				if c, err = keys.resolveOp(c, doc); err != nil {
+					return nil, err
+				}
+				concrete[j] = c
+			}
+			if limits != nil {
+				if copied, err = limits.copied(len(ops)+j, c, doc, copied); err != nil {
+					return nil, err
+				}
+			}
+			if doc, err = c.apply(doc); err != nil {
+				return nil, err
+			}
+			if limits != nil {
+				if err = limits.checkDocument(doc); err != nil {
+					return nil, err
+				}
+			}
+		}
+		ops = append(ops, concrete...)
+	}
+	return &Patcher{ops: ops}, nil
+}
+
+// wildcards parses a wildcard path, returning nil for a plain JSON Pointer.
+func wildcards(path string) ([]step, error) {
+	if strings.HasPrefix(path, "$") {
+		return parseJSONPath(path)
+	}
+	ptr, err := newJSONPointer(path)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]step, len(ptr))
+	plain := true
+	for i, t := range ptr {
+		switch t {
+		case "*":
+			steps[i].kind, plain = anyStep, false
+		case "**":
+			steps[i].kind, plain = descendStep, false
+		default:
+			steps[i] = step{childStep, t}
+		}
+	}
+	if plain {
+		return nil, nil
+	}
+	return steps, nil
+}
+
+func parseJSONPath(path string) ([]step, error) {
+	bad := func() ([]step, error) {
+		return nil, fmt.Errorf("rfc6902: invalid JSONPath %q", path)
+	}
+	steps := make([]step, 0)
+	s := path[1:]
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			steps = append(steps, step{kind: descendStep})
+			s = s[2:]
+			if strings.HasPrefix(s, "[") {
+				continue
+			}
+		case s[0] == '.':
+			s = s[1:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return bad()
+			}
+			sel := s[1:end]
+			if len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0] {
+				steps = append(steps, step{childStep, reftoken(encode(sel[1 : len(sel)-1]))})
+			} else if sel == "*" {
+				steps = append(steps, step{kind: anyStep})
+			} else if n, err := strconv.Atoi(sel); err == nil && n >= 0 {
+				steps = append(steps, step{childStep, indexToken(n)})
+			} else {
+				return bad()
+			}
+			s = s[end+1:]
+			continue
+		default:
+			return bad()
+		}
+
+		// a member name after "." or ".."
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		switch name := s[:end]; name {
+		case "":
+			return bad()
+		case "*":
+			steps = append(steps, step{kind: anyStep})
+		default:
+			steps = append(steps, step{childStep, reftoken(encode(name))})
+		}
+		s = s[end:]
+	}
+	return steps, nil
+}
+
+// match appends the locations below at that steps select in v.  Unless strict
+// is set a final member need not exist.
+func match(steps []step, at jsonptr, v interface{}, strict bool, out *[]jsonptr) {
+	if len(steps) == 0 {
+		*out = append(*out, at)
+		return
+	}
+	s := steps[0]
+	switch s.kind {
+	case childStep:
+		c, ok := member(v, s.token)
+		if ok {
+			match(steps[1:], at.child(s.token), c, strict, out)
+		} else if len(steps) == 1 && !strict && container(v) {
+			*out = append(*out, at.child(s.token))
+		}
+	case anyStep:
+		each(v, func(t reftoken, c interface{}) {
+			match(steps[1:], at.child(t), c, strict, out)
+		})
+	case descendStep:
+		match(steps[1:], at, v, true, out)
+		each(v, func(t reftoken, c interface{}) {
+			match(steps, at.child(t), c, true, out)
+		})
+	}
+}
+
+// member returns the member or element of v named by t.
+func member(v interface{}, t reftoken) (interface{}, bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		c, ok := x[t.token()]
+		return c, ok
+	case []interface{}:
+		i, err := strconv.Atoi(string(t))
+		if err != nil || i < 0 || i >= len(x) {
+			return nil, false
+		}
+		return x[i], true
+	}
+	return nil, false
+}
+
+func container(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// each calls f for every member of an object, in sorted order, or element of
+// an array.
+func each(v interface{}, f func(t reftoken, c interface{})) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(x) {
+			f(reftoken(encode(k)), x[k])
+		}
+	case []interface{}:
+		for i, c := range x {
+			f(indexToken(i), c)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Expand(t *testing.T) {
+	doc := `{"friends": [{"name": "a", "tags": []}, {"name": "b", "tags": ["x"]}, {"id": 3}], "*": 0}`
+	tests := []struct {
+		patch, expected string
+	}{
+		{`[{"op": "replace", "path": "/friends/*/name", "value": "z"}]`,
+			`[{"op": "replace", "path": "/friends/1/name", "value": "z"}, {"op": "replace", "path": "/friends/0/name", "value": "z"}]`},
+		{`[{"op": "add", "path": "/friends/*/active", "value": true}]`,
+			`[{"op": "add", "path": "/friends/2/active", "value": true}, {"op": "add", "path": "/friends/1/active", "value": true}, {"op": "add", "path": "/friends/0/active", "value": true}]`},
+		{`[{"op": "add", "path": "/friends/*/tags/-", "value": "y"}]`,
+			`[{"op": "add", "path": "/friends/1/tags/-", "value": "y"}, {"op": "add", "path": "/friends/0/tags/-", "value": "y"}]`},
+		{`[{"op": "remove", "path": "/friends/*"}]`,
+			`[{"op": "remove", "path": "/friends/2"}, {"op": "remove", "path": "/friends/1"}, {"op": "remove", "path": "/friends/0"}]`},
+		{`[{"op": "remove", "path": "/**/name"}]`,
+			`[{"op": "remove", "path": "/friends/1/name"}, {"op": "remove", "path": "/friends/0/name"}]`},
+		{`[{"op": "test", "path": "$.friends[*].tags[0]", "value": "x"}]`,
+			`[{"op": "test", "path": "/friends/1/tags/0", "value": "x"}]`},
+		{`[{"op": "remove", "path": "$..id"}, {"op": "replace", "path": "$['*']", "value": 1}]`,
+			`[{"op": "remove", "path": "/friends/2/id"}, {"op": "replace", "path": "/*", "value": 1}]`},
+		{`[{"op": "remove", "path": "$.friends[2]"}, {"op": "add", "path": "/nobody/*", "value": 1}]`,
+			`[{"op": "remove", "path": "/friends/2"}]`},
+		// plain pointers are left alone
+		{`[{"op": "move", "from": "/friends/0", "path": "/first"}]`,
+			`[{"op": "move", "from": "/friends/0", "path": "/first"}]`},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: ParsePatch failed: %s", i, err)
+		}
+		e, err := p.Expand([]byte(doc))
+		if err != nil {
+			t.Errorf("%d: Expand failed: %s", i, err)
+			continue
+		}
+		actual, _ := e.MarshalJSON()
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+}
+
+func Test_Expand_Errors(t *testing.T) {
+	for i, patch := range []string{
+		`[{"op": "move", "from": "/*", "path": "/a"}]`,
+		`[{"op": "remove", "path": "$.a["}]`,
+		`[{"op": "remove", "path": "$.a[x]"}]`,
+		`[{"op": "remove", "path": "$a"}]`,
+		`[{"op": "remove", "path": "$.a."}]`,
+		`[{"op": "test", "path": "/*/x", "value": 1}]`,
+	} {
+		p, _ := ParsePatch(strings.NewReader(patch))
+		if _, err := p.Expand([]byte(`{"a": {"x": {}}}`)); err == nil {
+			t.Errorf("%d: expected an error for %s", i, patch)
+		}
+	}
+}
+
+func Test_ApplyOptions_Wildcards(t *testing.T) {
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/*/isActive", "value": false}]`))
+	actual, err := p.ApplyWithOptions([]byte(largedoc), &ApplyOptions{Wildcards: true})
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	doc := um(string(actual)).([]interface{})
+	for i, e := range doc {
+		if e.(map[string]interface{})["isActive"] != false {
+			t.Errorf("%d: isActive was not replaced", i)
+		}
+	}
+
+	// policies see the expanded paths
+	o := &ApplyOptions{Wildcards: true, Policy: &Policy{Rules: []Rule{{Effect: Deny, Pattern: "/3/isActive"}}}}
+	if _, err := p.ApplyWithOptions([]byte(largedoc), o); err == nil {
+		t.Errorf("expected a policy violation")
+	}
+
+	// without the option "*" is a member name
+	if _, err := p.Apply([]byte(largedoc)); err == nil {
+		t.Errorf("expected an error without Wildcards")
+	}
+}
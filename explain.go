@@ -0,0 +1,223 @@
+package rfc6902
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format selects how a patch or diff is rendered for people.
+type Format int
+
+const (
+	// FormatPlain is plain text.
+	FormatPlain Format = iota
+	// FormatANSI is text colored with ANSI escape sequences for terminals.
+	FormatANSI
+	// FormatMarkdown is Markdown, for documents and pull request comments.
+	FormatMarkdown
+	// FormatHTML is an HTML fragment.
+	FormatHTML
+	// FormatJSON is a JSON document.
+	FormatJSON
+)
+
+// ANSI escape sequences used by FormatANSI.
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiReset  = "\x1b[0m"
+)
+
+// contextWidth limits the length of the context values Explain prints.
+const contextWidth = 60
+
+// Explain renders how the patch changes the JSON document b as plain text,
+// see ExplainAs.
+func (p *Patcher) Explain(b []byte) (string, error) {
+	return p.ExplainAs(b, FormatPlain)
+}
+
+/*
+ExplainAs renders how the patch changes the JSON document b, in the style of
+a unified diff, as plain text, colored for terminals or as a diff code block
+in Markdown.  Every operation gets a hunk headed by the operation and its
+path, listing the values it removes prefixed by "-" and those it adds
+prefixed by "+":
+
+	@@ replace /name @@
+	- "old"
+	+ "new"
+
+A move shows the value at its source and its destination, a copy the value
+at its destination.  Insertions into and removals from arrays show the
+neighbouring elements as context, prefixed by a space, and a test the value
+it expects prefixed by "=".  Each operation is explained against the document
+as the operations before it left it; an error is returned if one does not
+apply.
+*/
+func (p *Patcher) ExplainAs(b []byte, f Format) (string, error) {
+	if f != FormatPlain && f != FormatANSI && f != FormatMarkdown {
+		return "", fmt.Errorf("rfc6902: unsupported format %d", f)
+	}
+	if len(b) <= 0 {
+		return "", errors.New("rfc6902: empty JSON document")
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", err
+	}
+
+	e := &explainer{format: f}
+	if f == FormatMarkdown {
+		e.WriteString("```diff\n")
+	}
+	for _, op := range p.ops {
+		next, err := op.apply(clone(doc))
+		if err != nil {
+			return "", err
+		}
+		if err = e.explain(op, doc, next); err != nil {
+			return "", err
+		}
+		doc = next
+	}
+	if f == FormatMarkdown {
+		e.WriteString("```\n")
+	}
+	return e.String(), nil
+}
+
+type explainer struct {
+	strings.Builder
+	format Format
+}
+
+// explain writes the hunk of op, which turned before into after.
+func (e *explainer) explain(op Operation, before, after interface{}) error {
+	ptr, err := newJSONPointer(op.Path)
+	if err != nil {
+		return err
+	}
+	ptr = resolve(ptr, before)
+
+	switch op.Op {
+	case "move":
+		from, err := newJSONPointer(op.From)
+		if err != nil {
+			return err
+		}
+		e.header("move " + from.path() + " -> " + ptr.path())
+		e.value('-', from.path(), lookup(from, before))
+		e.value('+', ptr.path(), lookup(ptr, after))
+	case "copy":
+		from, err := newJSONPointer(op.From)
+		if err != nil {
+			return err
+		}
+		e.header("copy " + from.path() + " -> " + ptr.path())
+		e.value('+', ptr.path(), lookup(ptr, after))
+	case "test":
+		e.header("test " + ptr.path())
+		e.value('=', "", op.Value)
+	default:
+		e.header(op.Op + " " + ptr.path())
+		e.change(ptr, before, after)
+	}
+	return nil
+}
+
+// change writes the values before and after at ptr.  When an array element
+// was inserted or removed the neighbouring elements are written as context,
+// labelled with their paths after the change.
+func (e *explainer) change(ptr jsonptr, before, after interface{}) {
+	if len(ptr) == 0 {
+		e.value('-', "", before)
+		e.value('+', "", after)
+		return
+	}
+	parent, last := ptr[:len(ptr)-1], ptr[len(ptr)-1]
+	old, wasArray := lookup(parent, before).([]interface{})
+	cur, isArray := lookup(parent, after).([]interface{})
+	n, err := strconv.Atoi(string(last))
+	if !wasArray || !isArray || len(old) == len(cur) || err != nil {
+		if v, ok := member(lookup(parent, before), last); ok {
+			e.value('-', "", v)
+		}
+		if v, ok := member(lookup(parent, after), last); ok {
+			e.value('+', "", v)
+		}
+		return
+	}
+
+	if n > 0 {
+		e.context(parent.child(indexToken(n-1)), old[n-1])
+	}
+	if len(cur) > len(old) {
+		e.value('+', ptr.path(), cur[n])
+		if n < len(old) {
+			e.context(parent.child(indexToken(n+1)), old[n])
+		}
+	} else {
+		e.value('-', ptr.path(), old[n])
+		if n+1 < len(old) {
+			e.context(parent.child(indexToken(n)), old[n+1])
+		}
+	}
+}
+
+func (e *explainer) header(s string) {
+	e.line(ansiCyan, "@@ "+s+" @@")
+}
+
+// value writes v, labelled with path when not empty, on lines starting with
+// prefix.
+func (e *explainer) value(prefix byte, path string, v interface{}) {
+	lines := strings.Split(display(v, "  "), "\n")
+	if path != "" {
+		lines[0] = path + ": " + lines[0]
+	}
+	color := ""
+	switch prefix {
+	case '-':
+		color = ansiRed
+	case '+':
+		color = ansiGreen
+	}
+	for _, l := range lines {
+		e.line(color, string(prefix)+" "+l)
+	}
+}
+
+// context writes the element v at ptr on a single line.
+func (e *explainer) context(ptr jsonptr, v interface{}) {
+	s := display(v, "")
+	if r := []rune(s); len(r) > contextWidth {
+		s = string(r[:contextWidth-3]) + "..."
+	}
+	e.line("", "  "+ptr.path()+": "+s)
+}
+
+func (e *explainer) line(color, s string) {
+	if e.format == FormatANSI && color != "" {
+		s = color + s + ansiReset
+	}
+	e.WriteString(s)
+	e.WriteByte('\n')
+}
+
+// display returns v as JSON without escaping HTML characters, indented by
+// indent when not empty.
+func display(v interface{}, indent string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	enc.Encode(v)
+	return strings.TrimSuffix(b.String(), "\n")
+}
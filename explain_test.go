@@ -0,0 +1,73 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Explain(t *testing.T) {
+	doc := `{"name": "a", "list": [1, 2, 3], "obj": {"k": "<v>"}}`
+	tests := []struct {
+		patch, expected string
+	}{
+		{`[{"op": "replace", "path": "/name", "value": "b"}]`,
+			"@@ replace /name @@\n- \"a\"\n+ \"b\"\n"},
+		{`[{"op": "add", "path": "/list/1", "value": 9}]`,
+			"@@ add /list/1 @@\n  /list/0: 1\n+ /list/1: 9\n  /list/2: 2\n"},
+		{`[{"op": "add", "path": "/list/-", "value": {"x": [true]}}]`,
+			"@@ add /list/3 @@\n  /list/2: 3\n+ /list/3: {\n+   \"x\": [\n+     true\n+   ]\n+ }\n"},
+		{`[{"op": "remove", "path": "/list/0"}]`,
+			"@@ remove /list/0 @@\n- /list/0: 1\n  /list/0: 2\n"},
+		{`[{"op": "add", "path": "/new", "value": "x"}, {"op": "remove", "path": "/obj/k"}]`,
+			"@@ add /new @@\n+ \"x\"\n@@ remove /obj/k @@\n- \"<v>\"\n"},
+		{`[{"op": "move", "from": "/obj", "path": "/list/0"}]`,
+			"@@ move /obj -> /list/0 @@\n- /obj: {\n-   \"k\": \"<v>\"\n- }\n+ /list/0: {\n+   \"k\": \"<v>\"\n+ }\n"},
+		{`[{"op": "copy", "from": "/name", "path": "/obj/n"}]`,
+			"@@ copy /name -> /obj/n @@\n+ /obj/n: \"a\"\n"},
+		{`[{"op": "test", "path": "/name", "value": "a"}]`,
+			"@@ test /name @@\n= \"a\"\n"},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: ParsePatch failed: %s", i, err)
+		}
+		actual, err := p.Explain([]byte(doc))
+		if err != nil {
+			t.Errorf("%d: Explain failed: %s", i, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, actual, test.expected)
+		}
+	}
+
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "remove", "path": "/x"}]`))
+	if _, err := p.Explain([]byte(doc)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func Test_ExplainAs(t *testing.T) {
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/a", "value": 2}]`))
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{FormatANSI, "\x1b[36m@@ replace /a @@\x1b[0m\n\x1b[31m- 1\x1b[0m\n\x1b[32m+ 2\x1b[0m\n"},
+		{FormatMarkdown, "```diff\n@@ replace /a @@\n- 1\n+ 2\n```\n"},
+	}
+	for i, test := range tests {
+		actual, err := p.ExplainAs([]byte(`{"a": 1}`), test.format)
+		if err != nil {
+			t.Fatalf("%d: ExplainAs failed: %s", i, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, actual, test.expected)
+		}
+	}
+	if _, err := p.ExplainAs([]byte(`{"a": 1}`), FormatHTML); err == nil {
+		t.Errorf("expected an error for FormatHTML")
+	}
+}
@@ -0,0 +1,123 @@
+package rfc6902
+
+import (
+	"errors"
+)
+
+// ErrorTypeMismatch is returned when an extended operation does not apply to
+// the type of its target or value.
+var ErrorTypeMismatch = errors.New("rfc6902: operation does not apply to this type")
+
+/*
+extended holds the non-standard operations enabled by Parser.Extended.  Each
+computes the new value of the target from its current value, nil and false
+when absent, and the value member of the operation:
+
+	inc                 add the number value to a number, absent is 0
+	str-append          append the string value to a string, absent is ""
+	array-union         append the elements of the array value not yet in the
+	                    array, absent is []
+	array-remove-value  remove every element equal to value from the array
+	merge               merge value into the target as a JSON Merge Patch
+	                    (RFC 7396)
+*/
+var extended = map[string]extendedOp{
+	"inc": func(old interface{}, exists bool, value interface{}) (interface{}, error) {
+		delta, ok := value.(float64)
+		if !ok {
+			return nil, ErrorTypeMismatch
+		}
+		if !exists {
+			return delta, nil
+		}
+		n, ok := old.(float64)
+		if !ok {
+			return nil, ErrorTypeMismatch
+		}
+		return n + delta, nil
+	},
+
+	"str-append": func(old interface{}, exists bool, value interface{}) (interface{}, error) {
+		suffix, ok := value.(string)
+		if !ok {
+			return nil, ErrorTypeMismatch
+		}
+		if !exists {
+			return suffix, nil
+		}
+		s, ok := old.(string)
+		if !ok {
+			return nil, ErrorTypeMismatch
+		}
+		return s + suffix, nil
+	},
+
+	"array-union": func(old interface{}, exists bool, value interface{}) (interface{}, error) {
+		add, ok := value.([]interface{})
+		if !ok {
+			return nil, ErrorTypeMismatch
+		}
+		a := make([]interface{}, 0)
+		if exists {
+			if a, ok = old.([]interface{}); !ok {
+				return nil, ErrorTypeMismatch
+			}
+		}
+		for _, e := range add {
+			if indexOf(a, e) < 0 {
+				a = append(a, e)
+			}
+		}
+		return a, nil
+	},
+
+	"array-remove-value": func(old interface{}, exists bool, value interface{}) (interface{}, error) {
+		if !exists {
+			return nil, ErrorInvalidJSONPath
+		}
+		a, ok := old.([]interface{})
+		if !ok {
+			return nil, ErrorTypeMismatch
+		}
+		kept := make([]interface{}, 0, len(a))
+		for _, e := range a {
+			if !sameValue(e, value) {
+				kept = append(kept, e)
+			}
+		}
+		return kept, nil
+	},
+
+	"merge": func(old interface{}, exists bool, value interface{}) (interface{}, error) {
+		return mergePatch(old, value), nil
+	},
+}
+
+// extendedOp computes the new value of an extended operation's target.
+type extendedOp func(old interface{}, exists bool, value interface{}) (interface{}, error)
+
+func (f extendedOp) Members() []string {
+	return []string{"value"}
+}
+
+func (f extendedOp) Apply(doc *Document, path Pointer, op Operation) error {
+	old, err := doc.Get(path)
+	exists := err == nil
+	value, err := f(clone(old), exists, clone(op.Value))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return doc.Replace(path, value)
+	}
+	return doc.Add(path, value)
+}
+
+func indexOf(a []interface{}, v interface{}) int {
+	for i, e := range a {
+		if sameValue(e, v) {
+			return i
+		}
+	}
+	return -1
+}
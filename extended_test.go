@@ -0,0 +1,92 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Extended(t *testing.T) {
+	doc := `{"n": 1, "s": "ab", "tags": ["a", "b"], "obj": {"x": 1, "y": 2}, "list": [1, 2, 1]}`
+	tests := []struct {
+		patch, expected string
+	}{
+		{`[{"op": "inc", "path": "/n", "value": 2}]`, `{"n": 3}`},
+		{`[{"op": "inc", "path": "/n", "value": -1.5}, {"op": "inc", "path": "/m", "value": 1}]`, `{"n": -0.5, "m": 1}`},
+		{`[{"op": "inc", "path": "/list/1", "value": 1}]`, `{"list": [1, 3, 1]}`},
+		{`[{"op": "str-append", "path": "/s", "value": "c"}, {"op": "str-append", "path": "/t", "value": "x"}]`, `{"s": "abc", "t": "x"}`},
+		{`[{"op": "array-union", "path": "/tags", "value": ["b", "c", "c"]}]`, `{"tags": ["a", "b", "c"]}`},
+		{`[{"op": "array-union", "path": "/new", "value": [1, 1]}]`, `{"new": [1]}`},
+		{`[{"op": "array-remove-value", "path": "/list", "value": 1}]`, `{"list": [2]}`},
+		{`[{"op": "array-remove-value", "path": "/tags", "value": "z"}]`, `{"tags": ["a", "b"]}`},
+		{`[{"op": "array-remove-value", "path": "/list", "value": null}]`, `{"list": [1, 2, 1]}`},
+		{`[{"op": "merge", "path": "/obj", "value": {"x": null, "z": {"a": 1}}}]`, `{"obj": {"y": 2, "z": {"a": 1}}}`},
+		{`[{"op": "merge", "path": "/other", "value": {"a": 1}}]`, `{"other": {"a": 1}}`},
+	}
+
+	ps := &Parser{Extended: true}
+	for i, test := range tests {
+		p, err := ps.Parse(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Parse failed: %s", i, err)
+		}
+		actual, err := p.Apply([]byte(doc))
+		if err != nil {
+			t.Errorf("%d: Apply failed: %s", i, err)
+			continue
+		}
+		// only the members named in expected are compared
+		exp := um(test.expected).(map[string]interface{})
+		act := um(string(actual)).(map[string]interface{})
+		for k, v := range exp {
+			if !sameValue(act[k], v) {
+				t.Errorf("%d: %s (actual) %v != %v (expected)", i, k, act[k], v)
+			}
+		}
+	}
+}
+
+func Test_Extended_Errors(t *testing.T) {
+	doc := []byte(`{"n": 1, "s": "ab", "tags": ["a"]}`)
+	tests := []struct {
+		patch string
+		err   error
+	}{
+		{`[{"op": "inc", "path": "/s", "value": 1}]`, ErrorTypeMismatch},
+		{`[{"op": "inc", "path": "/n", "value": "1"}]`, ErrorTypeMismatch},
+		{`[{"op": "str-append", "path": "/n", "value": "x"}]`, ErrorTypeMismatch},
+		{`[{"op": "array-union", "path": "/tags", "value": "x"}]`, ErrorTypeMismatch},
+		{`[{"op": "array-union", "path": "/s", "value": ["x"]}]`, ErrorTypeMismatch},
+		{`[{"op": "array-remove-value", "path": "/none", "value": 1}]`, ErrorInvalidJSONPath},
+		{`[{"op": "array-remove-value", "path": "/n", "value": 1}]`, ErrorTypeMismatch},
+		{`[{"op": "inc", "path": "/a/b", "value": 1}]`, ErrorInvalidJSONPath},
+	}
+	ps := &Parser{Extended: true}
+	for i, test := range tests {
+		p, err := ps.Parse(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Parse failed: %s", i, err)
+		}
+		if _, err := p.Apply(doc); err != test.err {
+			t.Errorf("%d: (actual) %v != %v (expected)", i, err, test.err)
+		}
+	}
+}
+
+func Test_Extended_Parse(t *testing.T) {
+	patch := `[{"op": "inc", "path": "/n", "value": 1}]`
+	if _, err := ParsePatch(strings.NewReader(patch)); err == nil {
+		t.Errorf("expected strict parsing to reject inc")
+	}
+	if _, err := (&Parser{Extended: true}).Parse(strings.NewReader(`[{"op": "merge", "path": "/n"}]`)); err == nil {
+		t.Errorf("expected an error for a missing value")
+	}
+
+	p, err := (&Parser{Extended: true}).Parse(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	b, _ := p.MarshalJSON()
+	if !jsonEqual(b, []byte(patch)) {
+		t.Errorf("(actual) %s != %s (expected)", b, patch)
+	}
+}
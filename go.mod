@@ -0,0 +1,5 @@
+module github.com/noahcampbell/rfc6902
+
+go 1.19
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,272 @@
+package rfc6902
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Media types of the patch documents accepted by PatchHandler.
+const (
+	JSONPatchType           = "application/json-patch+json"
+	MergePatchType          = "application/merge-patch+json"
+	StrategicMergePatchType = "application/strategic-merge-patch+json"
+)
+
+var (
+	// ErrorNotFound is returned by a Resource without a document to patch.
+	ErrorNotFound = errors.New("rfc6902: resource not found")
+	// ErrorEditConflict is returned by Resource.Save when the document was
+	// modified after it was loaded.
+	ErrorEditConflict = errors.New("rfc6902: resource modified concurrently")
+)
+
+// DefaultMaxBodySize bounds the request body of a PatchHandler without
+// MaxBodySize.
+const DefaultMaxBodySize = 1 << 20
+
+// Resource loads and stores the JSON documents patched by a PatchHandler.
+// Entity tags are given as they appear in an ETag header, quotes included.
+type Resource interface {
+	// Load returns the document addressed by r and its entity tag.
+	Load(r *http.Request) (doc []byte, etag string, err error)
+	// Save replaces the document addressed by r, which was loaded with etag,
+	// and returns the new entity tag.
+	Save(r *http.Request, doc []byte, etag string) (newETag string, err error)
+}
+
+/*
+PatchHandler serves PATCH requests (RFC 5789) carrying a JSON Patch, a JSON
+Merge Patch (RFC 7396) or a strategic merge patch document.  The merge keys of
+strategic merge patches are taken from Options.Keys.
+
+The document is loaded from Resource, checked against an If-Match header,
+patched and saved.  The patched document is returned with its new ETag.  A
+merge patch is turned into the equivalent JSON Patch first, so Parser and
+Options apply to every kind of patch.  Documents are read and written with
+Options.Codec, and returned with its media type.
+
+Failures are reported with a problem+json body (RFC 7807):
+
+	400 malformed patch document
+	403 patch denied by Options.Policy
+	404 ErrorNotFound from Resource
+	405 method other than PATCH
+	409 failed test operation, ErrorEditConflict from Resource.Save
+	412 If-Match does not match the current entity tag
+	413 body beyond MaxBodySize, patch or document beyond the Limits of
+	    Parser or Options
+	415 unsupported Content-Type
+	422 patch cannot be applied, or the result violates a schema
+*/
+type PatchHandler struct {
+	Resource Resource
+	// Parser reads JSON Patch documents, ParsePatch is used when nil.
+	Parser *Parser
+	// Options customise Apply, may be nil.
+	Options *ApplyOptions
+	// MaxBodySize bounds the request body in bytes, DefaultMaxBodySize when
+	// 0.  A negative size sets no limit.
+	MaxBodySize int64
+}
+
+// Middleware returns a handler serving PATCH requests with h and passing any
+// other request to next.
+func (h *PatchHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (h *PatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		w.Header().Set("Allow", http.MethodPatch)
+		problem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mt != JSONPatchType && mt != MergePatchType && mt != StrategicMergePatchType) {
+		w.Header().Set("Accept-Patch", JSONPatchType+", "+MergePatchType+", "+StrategicMergePatchType)
+		problem(w, http.StatusUnsupportedMediaType, nil)
+		return
+	}
+	if max := h.maxBodySize(); max >= 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var me *http.MaxBytesError
+		if errors.As(err, &me) {
+			problem(w, http.StatusRequestEntityTooLarge, err)
+		} else {
+			problem(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	var p *Patcher
+	var merge interface{}
+	if mt == JSONPatchType {
+		ps := h.Parser
+		if ps == nil {
+			ps = new(Parser)
+		}
+		if p, err = ps.Parse(bytes.NewReader(body)); err != nil {
+			problem(w, parseStatus(err), err)
+			return
+		}
+	} else if err = json.Unmarshal(body, &merge); err != nil {
+		problem(w, http.StatusBadRequest, err)
+		return
+	}
+
+	doc, etag, err := h.Resource.Load(r)
+	if err != nil {
+		problem(w, resourceStatus(err), err)
+		return
+	}
+	if !ifMatch(r.Header.Get("If-Match"), etag) {
+		problem(w, http.StatusPreconditionFailed, nil)
+		return
+	}
+
+	if mt != JSONPatchType {
+		if p, err = h.mergeToPatch(doc, merge, mt == StrategicMergePatchType); err != nil {
+			problem(w, parseStatus(err), err)
+			return
+		}
+	}
+	patched, err := p.ApplyWithOptions(doc, h.Options)
+	if err != nil {
+		problem(w, applyStatus(err), err)
+		return
+	}
+
+	if etag, err = h.Resource.Save(r, patched, etag); err != nil {
+		problem(w, resourceStatus(err), err)
+		return
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("Content-Type", mediaType(h.Options.codec()))
+	w.Write(patched)
+}
+
+func (h *PatchHandler) maxBodySize() int64 {
+	if h.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return h.MaxBodySize
+}
+
+// mergeToPatch returns the JSON Patch doing what merge, a strategic merge
+// patch if strategic is set, does to doc.  The patch is checked like a JSON
+// Patch read by Parser.
+func (h *PatchHandler) mergeToPatch(doc []byte, merge interface{}, strategic bool) (*Patcher, error) {
+	v, err := h.Options.codec().Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	var p *Patcher
+	if strategic {
+		var keys Keys
+		if h.Options != nil {
+			keys = h.Options.Keys
+		}
+		merged, err := strategicMerge(jsonptr{}, clone(v), merge, keys)
+		if err != nil {
+			return nil, err
+		}
+		p = &Patcher{ops: diffWith(jsonptr{}, v, merged, keys)}
+	} else {
+		p = &Patcher{ops: diff(jsonptr{}, v, mergePatch(clone(v), merge))}
+	}
+	if h.Parser != nil && h.Parser.Limits != nil {
+		if err := h.Parser.Limits.checkPatch(p); err != nil {
+			return nil, err
+		}
+	}
+	if h.Parser != nil && h.Parser.Schema != nil {
+		if err := h.Parser.Schema.checkPatch(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ifMatch reports whether the If-Match header value allows etag, using
+// strong comparison (RFC 7232 section 3.1).
+func ifMatch(header, etag string) bool {
+	if header == "" {
+		return true
+	}
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || (t == etag && !strings.HasPrefix(t, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStatus(err error) int {
+	var se *SchemaError
+	var le *LimitError
+	switch {
+	case errors.As(err, &se):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &le):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func applyStatus(err error) int {
+	var pv *PolicyViolation
+	var le *LimitError
+	switch {
+	case errors.Is(err, ErrorTestFailed):
+		return http.StatusConflict
+	case errors.As(err, &pv):
+		return http.StatusForbidden
+	case errors.As(err, &le):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusUnprocessableEntity
+}
+
+func resourceStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrorEditConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// problem writes a problem details response (RFC 7807).
+func problem(w http.ResponseWriter, status int, err error) {
+	v := struct {
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+		Detail string `json:"detail,omitempty"`
+	}{"about:blank", http.StatusText(status), status, ""}
+	if err != nil && status != http.StatusInternalServerError {
+		v.Detail = err.Error()
+	}
+	b, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,227 @@
+package rfc6902
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testResource struct {
+	doc     []byte
+	version int
+	stale   bool
+}
+
+func (r *testResource) etag() string {
+	return `"` + strconv.Itoa(r.version) + `"`
+}
+
+func (r *testResource) Load(req *http.Request) ([]byte, string, error) {
+	if r.doc == nil {
+		return nil, "", ErrorNotFound
+	}
+	return r.doc, r.etag(), nil
+}
+
+func (r *testResource) Save(req *http.Request, doc []byte, etag string) (string, error) {
+	if r.stale || etag != r.etag() {
+		return "", ErrorEditConflict
+	}
+	r.doc = doc
+	r.version++
+	return r.etag(), nil
+}
+
+func Test_PatchHandler(t *testing.T) {
+	tests := []struct {
+		method, contentType, ifMatch, body string
+		status                             int
+		expected                           string
+	}{
+		{"PATCH", JSONPatchType, "", `[{"op": "replace", "path": "/a", "value": 2}]`, 200, `{"a": 2, "b": [1]}`},
+		{"PATCH", JSONPatchType + "; charset=utf-8", `"1"`, `[{"op": "add", "path": "/b/-", "value": 2}]`, 200, `{"a": 1, "b": [1, 2]}`},
+		{"PATCH", JSONPatchType, `"0", *`, `[{"op": "remove", "path": "/a"}]`, 200, `{"b": [1]}`},
+		{"PATCH", MergePatchType, "", `{"a": null, "c": {"d": true}}`, 200, `{"b": [1], "c": {"d": true}}`},
+		{"PATCH", JSONPatchType, `"0"`, `[{"op": "remove", "path": "/a"}]`, 412, ""},
+		{"PATCH", JSONPatchType, `W/"1"`, `[{"op": "remove", "path": "/a"}]`, 412, ""},
+		{"PATCH", "application/json", "", `[]`, 415, ""},
+		{"PATCH", "", "", `[]`, 415, ""},
+		{"PATCH", JSONPatchType, "", `{"op": "remove"`, 400, ""},
+		{"PATCH", JSONPatchType, "", `[{"op": "remove"}]`, 400, ""},
+		{"PATCH", MergePatchType, "", `{"a": }`, 400, ""},
+		{"PATCH", MergePatchType, "", `null`, 200, `null`},
+		{"PATCH", StrategicMergePatchType, "", `{"a": null, "$deleteFromPrimitiveList/b": [1]}`, 200, `{"b": []}`},
+		{"PATCH", StrategicMergePatchType, "", `{"a": {"$patch": "bogus"}}`, 400, ""},
+		{"PATCH", JSONPatchType, "", `[{"op": "remove", "path": "/x"}]`, 422, ""},
+		{"PATCH", JSONPatchType, "", `[{"op": "test", "path": "/a", "value": 2}]`, 409, ""},
+		{"PUT", JSONPatchType, "", `[]`, 405, ""},
+	}
+
+	for i, test := range tests {
+		res := &testResource{doc: []byte(`{"a": 1, "b": [1]}`), version: 1}
+		h := &PatchHandler{Resource: res}
+		req := httptest.NewRequest(test.method, "/doc", strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		if test.ifMatch != "" {
+			req.Header.Set("If-Match", test.ifMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("%d: (actual) %d != %d (expected): %s", i, w.Code, test.status, w.Body)
+			continue
+		}
+		if test.status != 200 {
+			checkProblem(t, i, w)
+			if res.version != 1 {
+				t.Errorf("%d: resource saved on failure", i)
+			}
+			continue
+		}
+		if !jsonEqual(w.Body.Bytes(), []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, w.Body, test.expected)
+		}
+		if !jsonEqual(res.doc, []byte(test.expected)) {
+			t.Errorf("%d: saved (actual) %s != %s (expected)", i, res.doc, test.expected)
+		}
+		if etag := w.Header().Get("ETag"); etag != `"2"` {
+			t.Errorf("%d: ETag (actual) %q != %q (expected)", i, etag, `"2"`)
+		}
+	}
+}
+
+func checkProblem(t *testing.T, i int, w *httptest.ResponseRecorder) {
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("%d: Content-Type (actual) %q != %q (expected)", i, ct, "application/problem+json")
+	}
+	var p struct{ Status int }
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || p.Status != w.Code {
+		t.Errorf("%d: bad problem body %s", i, w.Body)
+	}
+}
+
+func Test_PatchHandler_Resource(t *testing.T) {
+	tests := []struct {
+		res    *testResource
+		status int
+	}{
+		{&testResource{}, 404},
+		{&testResource{doc: []byte(`{"a": 1}`), stale: true}, 409},
+	}
+	for i, test := range tests {
+		h := &PatchHandler{Resource: test.res}
+		req := httptest.NewRequest("PATCH", "/doc", strings.NewReader(`[{"op": "remove", "path": "/a"}]`))
+		req.Header.Set("Content-Type", JSONPatchType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%d: (actual) %d != %d (expected)", i, w.Code, test.status)
+		}
+		checkProblem(t, i, w)
+	}
+}
+
+func Test_PatchHandler_Options(t *testing.T) {
+	schema, _ := ParseSchema(strings.NewReader(`{"properties": {"a": {"type": "integer"}}, "required": ["a"]}`))
+	tests := []struct {
+		h           *PatchHandler
+		contentType string
+		body        string
+		status      int
+	}{
+		{&PatchHandler{Options: &ApplyOptions{Policy: &Policy{Rules: []Rule{{Effect: Deny, Pattern: "/a"}}}}},
+			JSONPatchType, `[{"op": "replace", "path": "/a", "value": 2}]`, 403},
+		{&PatchHandler{Options: &ApplyOptions{Policy: &Policy{Rules: []Rule{{Effect: Deny, Pattern: "/a"}}}}},
+			MergePatchType, `{"a": 2}`, 403},
+		{&PatchHandler{Options: &ApplyOptions{Schema: schema}},
+			JSONPatchType, `[{"op": "replace", "path": "/a", "value": "x"}]`, 422},
+		{&PatchHandler{Parser: &Parser{Schema: schema}},
+			JSONPatchType, `[{"op": "remove", "path": "/a"}]`, 422},
+		{&PatchHandler{Parser: &Parser{Schema: schema}},
+			MergePatchType, `{"a": null}`, 422},
+		{&PatchHandler{Parser: &Parser{Limits: &Limits{MaxOperations: 1}}},
+			JSONPatchType, `[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/a", "value": 2}]`, 413},
+		{&PatchHandler{Options: &ApplyOptions{Limits: &Limits{MaxDocumentSize: 10}}},
+			MergePatchType, `{"b": "long enough"}`, 413},
+		{&PatchHandler{Parser: &Parser{Limits: &Limits{MaxOperations: 1}}},
+			MergePatchType, `{"a": 2, "b": 3}`, 413},
+		{&PatchHandler{Parser: &Parser{Limits: &Limits{MaxValueSize: 4}}},
+			StrategicMergePatchType, `{"b": "long enough"}`, 413},
+		{&PatchHandler{MaxBodySize: 8},
+			MergePatchType, `{"a": 2, "b": 3}`, 413},
+		{&PatchHandler{MaxBodySize: -1},
+			MergePatchType, `{"a": 2, "b": 3}`, 200},
+	}
+	for i, test := range tests {
+		test.h.Resource = &testResource{doc: []byte(`{"a": 1}`), version: 1}
+		req := httptest.NewRequest("PATCH", "/doc", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		w := httptest.NewRecorder()
+		test.h.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%d: (actual) %d != %d (expected): %s", i, w.Code, test.status, w.Body)
+		}
+	}
+}
+
+func Test_PatchHandler_Codec(t *testing.T) {
+	doc, _ := CBOR.Encode(map[string]interface{}{"a": 1.0})
+	res := &testResource{doc: doc, version: 1}
+	h := &PatchHandler{Resource: res, Options: &ApplyOptions{Codec: CBOR}}
+	req := httptest.NewRequest("PATCH", "/doc", strings.NewReader(`{"b": 2}`))
+	req.Header.Set("Content-Type", MergePatchType)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("(actual) %d != 200 (expected): %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/cbor" {
+		t.Errorf("(actual) %s != application/cbor (expected)", ct)
+	}
+	if !bytes.Equal(w.Body.Bytes(), res.doc) {
+		t.Errorf("(actual) %x != %x (expected)", w.Body.Bytes(), res.doc)
+	}
+	v, err := CBOR.Decode(res.doc)
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if b, _ := json.Marshal(v); !jsonEqual(b, []byte(`{"a": 1, "b": 2}`)) {
+		t.Errorf("(actual) %s != %s (expected)", b, `{"a": 1, "b": 2}`)
+	}
+}
+
+func Test_PatchHandler_Middleware(t *testing.T) {
+	h := &PatchHandler{Resource: &testResource{doc: []byte(`{}`)}}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	srv := httptest.NewServer(h.Middleware(next))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusTeapot {
+		t.Errorf("GET (actual) %d != %d (expected)", res.StatusCode, http.StatusTeapot)
+	}
+
+	req, _ := http.NewRequest("PATCH", srv.URL, strings.NewReader(`[{"op": "add", "path": "/a", "value": 1}]`))
+	req.Header.Set("Content-Type", JSONPatchType)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("PATCH (actual) %d != %d (expected)", res.StatusCode, http.StatusOK)
+	}
+}
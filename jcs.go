@@ -0,0 +1,207 @@
+package rfc6902
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+/*
+CanonicalJSON is a Codec writing the JSON Canonicalization Scheme (RFC 8785):
+no whitespace, object members sorted by the UTF-16 code units of their names,
+numbers written like ECMAScript and strings escaping only what JSON requires.
+Equal documents are written to the same bytes by any JCS implementation, so
+its output can be hashed and signed.  Binary strings are written in base64.
+*/
+var CanonicalJSON Codec = jcsCodec{}
+
+type jcsCodec struct{}
+
+func (jcsCodec) Decode(b []byte) (interface{}, error) {
+	return JSON.Decode(b)
+}
+
+func (jcsCodec) MediaType() string { return "application/json" }
+
+func (jcsCodec) Encode(v interface{}) ([]byte, error) {
+	return appendCanonical(nil, v)
+}
+
+// Canonicalize rewrites the JSON document b in its canonical form (RFC 8785).
+func Canonicalize(b []byte) ([]byte, error) {
+	v, err := CanonicalJSON.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return CanonicalJSON.Encode(v)
+}
+
+// Hash returns the SHA-256 hash of the canonical form of the JSON document
+// doc, written as "sha256:" followed by the hash in hex.
+func Hash(doc []byte) (string, error) {
+	c, err := Canonicalize(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(c)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func appendCanonical(b []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, x), nil
+	case float64:
+		return appendNumber(b, x)
+	case string:
+		return appendString(b, x), nil
+	case []byte:
+		return appendString(b, base64.StdEncoding.EncodeToString(x)), nil
+	case []interface{}:
+		b = append(b, '[')
+		for i, e := range x {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if b, err = appendCanonical(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return utf16Less(keys[i], keys[j])
+		})
+		b = append(b, '{')
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(appendString(b, k), ':')
+			if b, err = appendCanonical(b, x[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, fmt.Errorf("rfc6902: unsupported value of type %T", v)
+}
+
+// appendNumber writes f like ECMAScript's Number.prototype.toString (RFC 8785
+// section 3.2.2.3).
+func appendNumber(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("rfc6902: NaN and Infinity are not JSON numbers")
+	}
+	if f == 0 {
+		return append(b, '0'), nil
+	}
+	if f < 0 {
+		b = append(b, '-')
+		f = -f
+	}
+
+	// the shortest digits that read back as f, and the position n of the
+	// decimal point relative to them
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mant, exp, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mant, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	n, k := x+1, len(digits)
+
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		for ; k < n; k++ {
+			b = append(b, '0')
+		}
+	case 0 < n && n <= 21:
+		b = append(append(append(b, digits[:n]...), '.'), digits[n:]...)
+	case -6 < n && n <= 0:
+		b = append(b, "0."...)
+		for ; n < 0; n++ {
+			b = append(b, '0')
+		}
+		b = append(b, digits...)
+	default:
+		b = append(b, digits[0])
+		if k > 1 {
+			b = append(append(b, '.'), digits[1:]...)
+		}
+		b = append(b, 'e')
+		if n-1 >= 0 {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(n-1), 10)
+	}
+	return b, nil
+}
+
+// appendString writes s escaping only quotes, backslashes and control
+// characters (RFC 8785 section 3.2.2.2).
+func appendString(b []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				b = append(b, "\ufffd"...)
+			} else {
+				b = append(b, s[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if c < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			} else {
+				b = append(b, c)
+			}
+		}
+		i++
+	}
+	return append(b, '"')
+}
+
+// utf16Less orders strings by their UTF-16 code units (RFC 8785 section
+// 3.2.3).
+func utf16Less(a, b string) bool {
+	x, y := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
@@ -0,0 +1,111 @@
+package rfc6902
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// RFC 8785 appendix B
+func Test_CanonicalJSON_Numbers(t *testing.T) {
+	tests := []struct {
+		bits     uint64
+		expected string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for i, test := range tests {
+		actual, err := CanonicalJSON.Encode(math.Float64frombits(test.bits))
+		if err != nil {
+			t.Errorf("%d: Encode failed: %s", i, err)
+			continue
+		}
+		if string(actual) != test.expected {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+
+	for _, f := range []float64{math.NaN(), math.Inf(1)} {
+		if _, err := CanonicalJSON.Encode(f); err == nil {
+			t.Errorf("expected an error for %v", f)
+		}
+	}
+}
+
+func Test_Canonicalize(t *testing.T) {
+	tests := []struct {
+		doc, expected string
+	}{
+		// RFC 8785 section 3.2.2
+		{`{
+			"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			"literals": [null, true, false]
+		}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		// RFC 8785 section 3.2.3
+		{`{
+			"\u20ac": "Euro Sign",
+			"\r": "Carriage Return",
+			"\ufb33": "Hebrew Letter Dalet With Dagesh",
+			"1": "One",
+			"\ud83d\ude00": "Emoji: Grinning Face",
+			"\u0080": "Control",
+			"\u00f6": "Latin Small Letter O With Diaeresis"
+		}`, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"},
+		{`{"html": "<a href=\"x\">&</a>"}`, `{"html":"<a href=\"x\">&</a>"}`},
+	}
+	for i, test := range tests {
+		actual, err := Canonicalize([]byte(test.doc))
+		if err != nil {
+			t.Errorf("%d: Canonicalize failed: %s", i, err)
+			continue
+		}
+		if string(actual) != test.expected {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+}
+
+func Test_Hash(t *testing.T) {
+	a, err := Hash([]byte(`{"a": 1.0, "b": [true]}`))
+	if err != nil {
+		t.Fatalf("Hash failed: %s", err)
+	}
+	b, _ := Hash([]byte(`{"b":[true],"a":1}`))
+	c, _ := Hash([]byte(`{"b":[true],"a":2}`))
+	if a != b || a == c || !strings.HasPrefix(a, "sha256:") {
+		t.Errorf("bad hashes %s %s %s", a, b, c)
+	}
+
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/b", "value": "<&>"}]`))
+	actual, err := p.ApplyWithOptions([]byte(`{"c": 1e21, "a": 1}`), &ApplyOptions{Codec: CanonicalJSON})
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	if string(actual) != `{"a":1,"b":"<&>","c":1e+21}` {
+		t.Errorf("(actual) %s != %s (expected)", actual, `{"a":1,"b":"<&>","c":1e+21}`)
+	}
+}
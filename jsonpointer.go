@@ -17,6 +17,21 @@ func (j jsonptr) remainder(i int) (s string) {
 	return
 }
 
+// return the escaped pointer, suitable for a path or from member
+func (j jsonptr) path() (s string) {
+	for _, ref := range j {
+		s += "/" + string(ref)
+	}
+	return
+}
+
+// return a new pointer with ref appended
+func (j jsonptr) child(ref reftoken) jsonptr {
+	c := make(jsonptr, len(j), len(j)+1)
+	copy(c, j)
+	return append(c, ref)
+}
+
 func (j jsonptr) String() (s string) {
 	for _, ref := range j {
 		s += "/" + ref.token()
@@ -40,6 +55,12 @@ func decode(in string) string {
 	return strings.Replace(in, "~0", "~", -1)
 }
 
+// encode according to Section 3. Syntax
+func encode(in string) string {
+	in = strings.Replace(in, "~", "~0", -1)
+	return strings.Replace(in, "/", "~1", -1)
+}
+
 func newRefToken(in string) reftoken {
 	if len(in) <= 0 {
 		panic("jsonptr cannot be formed from zero length string")
@@ -76,7 +97,7 @@ func newJSONPointer(path string) (head jsonptr, err error) {
 			head = append(head, newRefToken(s[:next]))
 			s = s[next:]
 		} else {
-			panic(fmt.Sprintf("field must start with '/': %q", s))
+			return nil, fmt.Errorf("field must start with '/': %q", s)
 		}
 	}
 	return
@@ -94,7 +94,7 @@ func Test_JSONPointer_ErrorHandling(t *testing.T) {
 		p := patcher{f, v}
 		_, err := p.value()
 		if err != ErrorInvalidJSONPath {
-			t.Errorf("%s: is a missing path but retunred err:", test.path, err)
+			t.Errorf("%s: is a missing path but retunred err: %v", test.path, err)
 		}
 	}
 }
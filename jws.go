@@ -0,0 +1,196 @@
+package rfc6902
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// ErrorBadSignature is returned when a patch does not match its signature.
+var ErrorBadSignature = errors.New("rfc6902: invalid patch signature")
+
+/*
+A SigningKey signs patches with a JSON Web Signature (RFC 7515).  Key selects
+the algorithm:
+
+	[]byte             HS256, HMAC with SHA-256
+	ed25519.PrivateKey EdDSA (RFC 8037)
+	*ecdsa.PrivateKey  ES256, ECDSA on the P-256 curve with SHA-256
+
+ID is written as the kid header parameter, naming the key to verify with.
+*/
+type SigningKey struct {
+	ID  string
+	Key interface{}
+}
+
+// VerificationKeys maps key ids to the keys verifying the signatures they
+// made: []byte for HS256, ed25519.PublicKey for EdDSA and *ecdsa.PublicKey
+// for ES256.
+type VerificationKeys map[string]interface{}
+
+// VerifiedPatcher is a patch whose signature was verified with the key
+// KeyID.
+type VerifiedPatcher struct {
+	*Patcher
+	KeyID     string
+	Algorithm string
+}
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+}
+
+/*
+Sign returns a detached JWS of the patch, in compact serialization with an
+empty payload (RFC 7515 appendix F).  The payload signed is the canonical form
+(RFC 8785) of the patch document, so the signature holds however the document
+is formatted in transit.
+*/
+func (p *Patcher) Sign(key SigningKey) (string, error) {
+	var alg string
+	switch key.Key.(type) {
+	case []byte:
+		alg = "HS256"
+	case ed25519.PrivateKey:
+		alg = "EdDSA"
+	case *ecdsa.PrivateKey:
+		alg = "ES256"
+	default:
+		return "", fmt.Errorf("rfc6902: unsupported signing key %T", key.Key)
+	}
+	payload, err := p.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	if payload, err = Canonicalize(payload); err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(jwsHeader{alg, key.ID})
+	if err != nil {
+		return "", err
+	}
+	input := signingInput(header, payload)
+
+	var sig []byte
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(input)
+		sig = mac.Sum(nil)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, input)
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("rfc6902: ES256 needs a P-256 key")
+		}
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", err
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(header) + ".." + enc.EncodeToString(sig), nil
+}
+
+// ParseVerifiedPatch reads a patch document from r, verifying it first with
+// the detached JWS jws and keys.
+func ParseVerifiedPatch(r io.Reader, jws string, keys VerificationKeys) (*VerifiedPatcher, error) {
+	return new(Parser).ParseVerified(r, jws, keys)
+}
+
+// ParseVerified reads a patch document from r like Parse, after verifying it
+// with the detached JWS jws, as made by Patcher.Sign.  The key is looked up
+// in keys by the kid of the signature and must be of the type its algorithm
+// needs.  A patch failing verification is not parsed and ErrorBadSignature
+// is returned.
+func (ps *Parser) ParseVerified(r io.Reader, jws string, keys VerificationKeys) (*VerifiedPatcher, error) {
+	if r == nil {
+		return nil, errors.New("reader is nil")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := Canonicalize(b)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return nil, errors.New("rfc6902: not a detached JWS")
+	}
+	enc := base64.RawURLEncoding
+	header, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	var h jwsHeader
+	if err = json.Unmarshal(header, &h); err != nil {
+		return nil, err
+	}
+	key, ok := keys[h.Kid]
+	if !ok {
+		return nil, fmt.Errorf("rfc6902: unknown key %q", h.Kid)
+	}
+	if !verifySignature(h.Alg, key, signingInput(header, payload), sig) {
+		return nil, ErrorBadSignature
+	}
+
+	p, err := ps.Parse(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	return &VerifiedPatcher{p, h.Kid, h.Alg}, nil
+}
+
+func signingInput(header, payload []byte) []byte {
+	enc := base64.RawURLEncoding
+	return []byte(enc.EncodeToString(header) + "." + enc.EncodeToString(payload))
+}
+
+// verifySignature reports whether sig is the signature of input with key by
+// alg.  It fails when key is not a key for alg.
+func verifySignature(alg string, key interface{}, input, sig []byte) bool {
+	switch k := key.(type) {
+	case []byte:
+		if alg != "HS256" {
+			return false
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(input)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case ed25519.PublicKey:
+		return alg == "EdDSA" && len(k) == ed25519.PublicKeySize && ed25519.Verify(k, input, sig)
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || k.Curve != elliptic.P256() || len(sig) != 64 {
+			return false
+		}
+		digest := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	}
+	return false
+}
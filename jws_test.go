@@ -0,0 +1,79 @@
+package rfc6902
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func Test_SignedPatch(t *testing.T) {
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	ecPriv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	keys := VerificationKeys{"hmac": secret, "ed": edPub, "ec": &ecPriv.PublicKey}
+
+	doc := `[{"op": "add", "path": "/a", "value": 1}]`
+	p, _ := ParsePatch(strings.NewReader(doc))
+	tests := []struct {
+		key SigningKey
+		alg string
+	}{
+		{SigningKey{"hmac", secret}, "HS256"},
+		{SigningKey{"ed", edPriv}, "EdDSA"},
+		{SigningKey{"ec", ecPriv}, "ES256"},
+	}
+	for i, test := range tests {
+		jws, err := p.Sign(test.key)
+		if err != nil {
+			t.Fatalf("%d: Sign failed: %s", i, err)
+		}
+		if parts := strings.Split(jws, "."); len(parts) != 3 || parts[1] != "" {
+			t.Errorf("%d: not a detached JWS: %s", i, jws)
+		}
+
+		// formatting does not matter
+		vp, err := ParseVerifiedPatch(strings.NewReader(`[ {"value": 1, "path": "/a", "op": "add"} ]`), jws, keys)
+		if err != nil {
+			t.Errorf("%d: ParseVerifiedPatch failed: %s", i, err)
+			continue
+		}
+		if vp.KeyID != test.key.ID || vp.Algorithm != test.alg {
+			t.Errorf("%d: (actual) %s %s != %s %s (expected)", i, vp.KeyID, vp.Algorithm, test.key.ID, test.alg)
+		}
+		actual, err := vp.Apply([]byte(`{}`))
+		if err != nil || !jsonEqual(actual, []byte(`{"a": 1}`)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, `{"a": 1}`)
+		}
+
+		if _, err = ParseVerifiedPatch(strings.NewReader(`[{"op": "add", "path": "/a", "value": 2}]`), jws, keys); err != ErrorBadSignature {
+			t.Errorf("%d: expected ErrorBadSignature for a modified patch, got %v", i, err)
+		}
+	}
+
+	// a signature made with a key of another type or id does not verify
+	jws, _ := p.Sign(SigningKey{"ed", secret})
+	if _, err := ParseVerifiedPatch(strings.NewReader(doc), jws, keys); err != ErrorBadSignature {
+		t.Errorf("expected ErrorBadSignature, got %v", err)
+	}
+	jws, _ = p.Sign(SigningKey{"other", secret})
+	if _, err := ParseVerifiedPatch(strings.NewReader(doc), jws, keys); err == nil {
+		t.Errorf("expected an error for an unknown key")
+	}
+
+	for i, jws := range []string{
+		"",
+		"eyJhbGciOiJub25lIn0.W10.",
+		"eyJhbGciOiJub25lIiwia2lkIjoiaG1hYyJ9..",
+		"!..x",
+	} {
+		if _, err := ParseVerifiedPatch(strings.NewReader(doc), jws, keys); err == nil {
+			t.Errorf("%d: expected an error for %q", i, jws)
+		}
+	}
+	if _, err := p.Sign(SigningKey{"x", "secret"}); err == nil {
+		t.Errorf("expected an error for an unsupported key")
+	}
+}
@@ -0,0 +1,229 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+Keys configures keyed array addressing, enabled by ApplyOptions.Keys.
+
+A reference token of the form [member=value] addresses the element of an array
+whose member has the given value, so /[id=692]/guid is the guid of the element
+with id 692 wherever it is in the array.  Values are compared with the string
+form of the member, so [id=692] matches 692 as well as "692", and the first
+matching element is addressed.
+
+Keys maps the pointers of arrays, where "*" matches any token, to the member
+identifying their elements.  In those arrays the short form [value] may be
+used, and CreateKeyedPatch matches elements by key.
+*/
+type Keys map[string]string
+
+// member returns the key member configured for the array at ptr, or "".
+func (k Keys) member(ptr jsonptr) string {
+	patterns := make([]string, 0, len(k))
+	for p := range k {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	for _, p := range patterns {
+		pat, err := newJSONPointer(p)
+		if err != nil {
+			continue
+		}
+		tokens := make([]string, len(pat))
+		for i, t := range pat {
+			tokens[i] = t.token()
+		}
+		if glob(tokens, ptr, false) {
+			return k[p]
+		}
+	}
+	return ""
+}
+
+// resolveOp returns op with the keyed tokens of its path and from replaced by
+// indices into doc.
+func (k Keys) resolveOp(op Operation, doc interface{}) (Operation, error) {
+	for _, s := range []*string{&op.Path, &op.From} {
+		if *s == "" {
+			continue
+		}
+		ptr, err := newJSONPointer(*s)
+		if err != nil {
+			return op, err
+		}
+		if ptr, err = k.resolve(ptr, doc); err != nil {
+			return op, err
+		}
+		*s = ptr.path()
+	}
+	return op, nil
+}
+
+// resolve walks ptr through doc, replacing keyed tokens addressing array
+// elements by their index.  Tokens below a missing location are left alone.
+func (k Keys) resolve(ptr jsonptr, doc interface{}) (jsonptr, error) {
+	out := make(jsonptr, len(ptr))
+	copy(out, ptr)
+	v := doc
+	for i, t := range ptr {
+		if a, ok := v.([]interface{}); ok {
+			if m, value, ok := keySelector(t); ok {
+				if m == "" {
+					if m = k.member(out[:i]); m == "" {
+						return nil, fmt.Errorf("rfc6902: no key configured for %q", out[:i].path())
+					}
+				}
+				idx := keyIndex(a, m, value)
+				if idx < 0 {
+					return nil, ErrorInvalidJSONPath
+				}
+				out[i] = indexToken(idx)
+			}
+		}
+		c, ok := member(v, out[i])
+		if !ok {
+			break
+		}
+		v = c
+	}
+	return out, nil
+}
+
+// keySelector parses a keyed token, returning an empty member for the short
+// form.
+func keySelector(t reftoken) (member, value string, ok bool) {
+	s := t.token()
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return "", "", false
+	}
+	s = s[1 : len(s)-1]
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return "", s, true
+}
+
+// keyOf returns the string form of the member m of e.
+func keyOf(e interface{}, m string) (string, bool) {
+	o, ok := e.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	v, ok := o[m]
+	if !ok {
+		return "", false
+	}
+	s, err := mapKey(v)
+	return s, err == nil
+}
+
+// keyIndex returns the index of the first element of a whose member m has
+// the string form value, or -1.
+func keyIndex(a []interface{}, m, value string) int {
+	for i, e := range a {
+		if s, ok := keyOf(e, m); ok && s == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// CreateKeyedPatch is CreatePatch, matching the elements of the arrays
+// configured in keys by their key instead of their position.  Their elements
+// are addressed with [member=value] tokens, so the patch applies as intended
+// even when those arrays were reordered in the meantime; it must be applied
+// with ApplyOptions.Keys set.  Arrays whose elements do not all have a
+// unique key are diffed by position.
+func CreateKeyedPatch(a, b []byte, keys Keys) (*Patcher, error) {
+	if len(a) <= 0 || len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return nil, err
+	}
+	return &Patcher{ops: diffWith(jsonptr{}, av, bv, keys)}, nil
+}
+
+// diffKeyed diffs arrays whose elements are identified by the member m.  ok
+// is false when an element lacks a unique key.
+func diffKeyed(path jsonptr, a, b []interface{}, m string, keys Keys) (ops []Operation, ok bool) {
+	ak, ok := elementKeys(a, m)
+	if !ok {
+		return nil, false
+	}
+	bk, ok := elementKeys(b, m)
+	if !ok {
+		return nil, false
+	}
+	posA := make(map[string]int, len(ak))
+	for i, key := range ak {
+		posA[key] = i
+	}
+	inB := make(map[string]bool, len(bk))
+	for _, key := range bk {
+		inB[key] = true
+	}
+	keyed := func(key string) jsonptr {
+		return path.child(reftoken(encode("[" + m + "=" + key + "]")))
+	}
+
+	var cur, want []string
+	for _, key := range ak {
+		if !inB[key] {
+			ops = append(ops, Operation{Op: "remove", Path: keyed(key).path()})
+		} else {
+			cur = append(cur, key)
+		}
+	}
+	for j, key := range bk {
+		if i, ok := posA[key]; ok {
+			ops = append(ops, diffWith(keyed(key), a[i], b[j], keys)...)
+			want = append(want, key)
+		}
+	}
+
+	// reorder the remaining elements, then insert the new ones
+	for j, key := range want {
+		if cur[j] == key {
+			continue
+		}
+		i := j + 1
+		for cur[i] != key {
+			i++
+		}
+		ops = append(ops, Operation{Op: "move", From: keyed(key).path(), Path: path.child(indexToken(j)).path()})
+		copy(cur[j+1:i+1], cur[j:i])
+		cur[j] = key
+	}
+	for j, key := range bk {
+		if _, ok := posA[key]; !ok {
+			ops = append(ops, Operation{Op: "add", Path: path.child(indexToken(j)).path(), Value: clone(b[j])})
+		}
+	}
+	return ops, true
+}
+
+// elementKeys returns the keys of the elements of a, ok is false unless every
+// element has a unique key.
+func elementKeys(a []interface{}, m string) ([]string, bool) {
+	keys := make([]string, len(a))
+	seen := make(map[string]bool, len(a))
+	for i, e := range a {
+		key, ok := keyOf(e, m)
+		if !ok || seen[key] {
+			return nil, false
+		}
+		keys[i], seen[key] = key, true
+	}
+	return keys, true
+}
@@ -0,0 +1,141 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Keys_Apply(t *testing.T) {
+	doc := `{"users": [{"id": 7, "name": "a", "tags": [{"k": "x", "v": 1}]}, {"id": "9", "name": "b"}], "list": [1, 2]}`
+	keys := Keys{"/users": "id", "/users/*/tags": "k"}
+	tests := []struct {
+		patch, expected string
+	}{
+		{`[{"op": "replace", "path": "/users/[id=9]/name", "value": "B"}]`,
+			`{"users": [{"id": 7, "name": "a", "tags": [{"k": "x", "v": 1}]}, {"id": "9", "name": "B"}], "list": [1, 2]}`},
+		{`[{"op": "remove", "path": "/users/[7]"}]`,
+			`{"users": [{"id": "9", "name": "b"}], "list": [1, 2]}`},
+		{`[{"op": "replace", "path": "/users/[7]/tags/[x]/v", "value": 2}]`,
+			`{"users": [{"id": 7, "name": "a", "tags": [{"k": "x", "v": 2}]}, {"id": "9", "name": "b"}], "list": [1, 2]}`},
+		{`[{"op": "move", "from": "/users/[id=7]", "path": "/users/-"}]`,
+			`{"users": [{"id": "9", "name": "b"}, {"id": 7, "name": "a", "tags": [{"k": "x", "v": 1}]}], "list": [1, 2]}`},
+		// an object member named like a keyed token is not resolved
+		{`[{"op": "add", "path": "/[id=1]", "value": 0}]`,
+			`{"users": [{"id": 7, "name": "a", "tags": [{"k": "x", "v": 1}]}, {"id": "9", "name": "b"}], "list": [1, 2], "[id=1]": 0}`},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: ParsePatch failed: %s", i, err)
+		}
+		actual, err := p.ApplyWithOptions([]byte(doc), &ApplyOptions{Keys: keys})
+		if err != nil {
+			t.Errorf("%d: ApplyWithOptions failed: %s", i, err)
+			continue
+		}
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+
+	for i, patch := range []string{
+		`[{"op": "remove", "path": "/users/[id=8]"}]`,
+		`[{"op": "remove", "path": "/list/[1]"}]`,
+		`[{"op": "remove", "path": "/users/[id=7]/tags/[y]"}]`,
+	} {
+		p, _ := ParsePatch(strings.NewReader(patch))
+		if _, err := p.ApplyWithOptions([]byte(doc), &ApplyOptions{Keys: keys}); err == nil {
+			t.Errorf("%d: expected an error for %s", i, patch)
+		}
+	}
+
+	// keyed tokens are only resolved when enabled
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "remove", "path": "/users/[id=9]"}]`))
+	if _, err := p.Apply([]byte(doc)); err == nil {
+		t.Errorf("expected an error without Keys")
+	}
+}
+
+func Test_Keys_LargeDoc(t *testing.T) {
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "test", "path": "/[id=692]/guid", "value": "48e4b239-598e-4aee-ab6e-50b9ebbc6219"}]`))
+	if _, err := p.ApplyWithOptions([]byte(largedoc), &ApplyOptions{Keys: Keys{}}); err != nil {
+		t.Errorf("ApplyWithOptions failed: %s", err)
+	}
+}
+
+func Test_CreateKeyedPatch(t *testing.T) {
+	keys := Keys{"/items": "id"}
+	tests := []struct {
+		a, b, expected string
+	}{
+		{`{"items": [{"id": 1, "v": "a"}, {"id": 2, "v": "b"}]}`,
+			`{"items": [{"id": 1, "v": "a"}, {"id": 2, "v": "B"}]}`,
+			`[{"op": "replace", "path": "/items/[id=2]/v", "value": "B"}]`},
+		{`{"items": [{"id": 1}, {"id": 2}, {"id": 3}]}`,
+			`{"items": [{"id": 3}, {"id": 4}, {"id": 1}]}`,
+			`[{"op": "remove", "path": "/items/[id=2]"}, {"op": "move", "from": "/items/[id=3]", "path": "/items/0"}, {"op": "add", "path": "/items/1", "value": {"id": 4}}]`},
+		// duplicate keys fall back to positions
+		{`{"items": [{"id": 1}, {"id": 1, "v": 0}]}`,
+			`{"items": [{"id": 1}, {"id": 1, "v": 1}]}`,
+			`[{"op": "replace", "path": "/items/1/v", "value": 1}]`},
+		// arrays without a configured key are diffed by position
+		{`{"other": [{"id": 1}, {"id": 2}]}`,
+			`{"other": [{"id": 2}]}`,
+			`[{"op": "remove", "path": "/other/0"}]`},
+	}
+
+	for i, test := range tests {
+		p, err := CreateKeyedPatch([]byte(test.a), []byte(test.b), keys)
+		if err != nil {
+			t.Fatalf("%d: CreateKeyedPatch failed: %s", i, err)
+		}
+		actual, _ := p.MarshalJSON()
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+		patched, err := p.ApplyWithOptions([]byte(test.a), &ApplyOptions{Keys: keys})
+		if err != nil {
+			t.Errorf("%d: ApplyWithOptions failed: %s", i, err)
+			continue
+		}
+		if !jsonEqual(patched, []byte(test.b)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, patched, test.b)
+		}
+	}
+
+	// a keyed patch still applies after the array was reordered
+	p, _ := CreateKeyedPatch([]byte(`{"items": [{"id": 1, "v": 0}, {"id": 2, "v": 0}]}`), []byte(`{"items": [{"id": 1, "v": 0}, {"id": 2, "v": 5}]}`), keys)
+	actual, err := p.ApplyWithOptions([]byte(`{"items": [{"id": 2, "v": 0}, {"id": 1, "v": 0}]}`), &ApplyOptions{Keys: keys})
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	if !jsonEqual(actual, []byte(`{"items": [{"id": 2, "v": 5}, {"id": 1, "v": 0}]}`)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, `{"items": [{"id": 2, "v": 5}, {"id": 1, "v": 0}]}`)
+	}
+}
+
+func Test_Keys_Options(t *testing.T) {
+	doc := []byte(`{"l": [{"id": 1, "v": 0, "owner": "a"}, {"id": 2, "v": 0, "owner": "b"}]}`)
+	keys := Keys{"/l": "id"}
+
+	// keyed tokens are resolved before the policy is checked
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/l/[id=2]/owner", "value": "c"}]`))
+	o := &ApplyOptions{Keys: keys, Policy: &Policy{Rules: []Rule{{Effect: Deny, Pattern: "/l/1/owner"}}}}
+	if _, err := p.ApplyWithOptions(doc, o); err == nil {
+		t.Errorf("expected a policy violation")
+	} else if v, ok := err.(*PolicyViolation); !ok || v.Pointer != "/l/1/owner" {
+		t.Errorf("(actual) %v != violation at /l/1/owner (expected)", err)
+	}
+
+	// and before wildcards are expanded
+	p, _ = ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/l/[id=2]/v", "value": 1}, {"op": "add", "path": "/l/*/seen", "value": true}]`))
+	actual, err := p.ApplyWithOptions(doc, &ApplyOptions{Keys: keys, Wildcards: true})
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	expected := `{"l": [{"id": 1, "v": 0, "owner": "a", "seen": true}, {"id": 2, "v": 1, "owner": "b", "seen": true}]}`
+	if !jsonEqual(actual, []byte(expected)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, expected)
+	}
+}
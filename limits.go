@@ -0,0 +1,162 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+Limits bounds the resources a patch from an untrusted source may use.  A zero
+field sets no limit.
+
+Sizes are measured in bytes of canonical JSON (RFC 8785), whatever the codec,
+and depths in levels of nested arrays and objects.  MaxCopyBytes bounds the
+sum of the sizes of the values copied or moved by all the operations of a
+patch, so a patch repeatedly copying a subtree into itself fails long before
+the document outgrows memory.
+
+Exceeded limits are reported as a *LimitError.  Parser stops reading a patch
+at the first operation over MaxOperations.
+*/
+type Limits struct {
+	MaxOperations int
+	// MaxPointerDepth bounds the reference tokens of a path or from.
+	MaxPointerDepth int
+	// MaxValueSize bounds the value of an operation.
+	MaxValueSize int
+	// MaxDocumentDepth and MaxDocumentSize bound the document before and
+	// after it is patched.
+	MaxDocumentDepth int
+	MaxDocumentSize  int
+	MaxCopyBytes     int
+}
+
+// LimitError is returned when a patch or document exceeds Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded field of Limits.
+	Limit string
+	Max   int
+	// Index is the offending operation, -1 for the document.
+	Index int
+}
+
+func (e *LimitError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("rfc6902: document exceeds %s of %d", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("rfc6902: operation %d exceeds %s of %d", e.Index, e.Limit, e.Max)
+}
+
+// checkPatch returns a *LimitError for the first operation of p exceeding
+// the limits.
+func (l *Limits) checkPatch(p *Patcher) error {
+	if l.MaxOperations > 0 && len(p.ops) > l.MaxOperations {
+		// the index of the first operation over the limit
+		first := l.MaxOperations
+		return &LimitError{"MaxOperations", l.MaxOperations, first}
+	}
+	for i, op := range p.ops {
+		if l.MaxPointerDepth > 0 {
+			// every reference token starts with a "/"
+			if strings.Count(op.Path, "/") > l.MaxPointerDepth || strings.Count(op.From, "/") > l.MaxPointerDepth {
+				return &LimitError{"MaxPointerDepth", l.MaxPointerDepth, i}
+			}
+		}
+		if l.MaxValueSize > 0 && op.Value != nil && encodedSize(op.Value, l.MaxValueSize) > l.MaxValueSize {
+			return &LimitError{"MaxValueSize", l.MaxValueSize, i}
+		}
+	}
+	return nil
+}
+
+// checkDocument returns a *LimitError when doc is too large or too deep.
+func (l *Limits) checkDocument(doc interface{}) error {
+	if l.MaxDocumentSize > 0 && encodedSize(doc, l.MaxDocumentSize) > l.MaxDocumentSize {
+		return &LimitError{"MaxDocumentSize", l.MaxDocumentSize, -1}
+	}
+	if l.MaxDocumentDepth > 0 && nesting(doc, l.MaxDocumentDepth) > l.MaxDocumentDepth {
+		return &LimitError{"MaxDocumentDepth", l.MaxDocumentDepth, -1}
+	}
+	return nil
+}
+
+// copied adds the size of the value the copy or move op at index takes from
+// doc to total, the bytes copied so far.
+func (l *Limits) copied(index int, op Operation, doc interface{}, total int) (int, error) {
+	if l.MaxCopyBytes <= 0 || (op.Op != "copy" && op.Op != "move") {
+		return total, nil
+	}
+	from, err := newJSONPointer(op.From)
+	if err != nil {
+		return total, err
+	}
+	total += encodedSize(lookup(resolve(from, doc), doc), l.MaxCopyBytes-total)
+	if total > l.MaxCopyBytes {
+		return total, &LimitError{"MaxCopyBytes", l.MaxCopyBytes, index}
+	}
+	return total, nil
+}
+
+// encodedSize returns the length of v in canonical JSON.  It stops counting
+// once the length exceeds max.
+func encodedSize(v interface{}, max int) int {
+	switch x := v.(type) {
+	case string:
+		return len(appendString(nil, x))
+	case float64:
+		b, _ := appendNumber(nil, x)
+		return len(b)
+	case []interface{}:
+		n := 2
+		for i, e := range x {
+			if i > 0 {
+				n++
+			}
+			if n += encodedSize(e, max-n); n > max {
+				return n
+			}
+		}
+		return n
+	case map[string]interface{}:
+		n := 2
+		for k, e := range x {
+			if n > 2 {
+				n++
+			}
+			n += len(appendString(nil, k)) + 1
+			if n += encodedSize(e, max-n); n > max {
+				return n
+			}
+		}
+		return n
+	}
+	b, _ := json.Marshal(v)
+	return len(b)
+}
+
+// nesting returns the depth of the arrays and objects in v, 0 for a scalar.
+// It stops descending below max.
+func nesting(v interface{}, max int) int {
+	if max < 0 {
+		return 0
+	}
+	deepest := 0
+	switch x := v.(type) {
+	case []interface{}:
+		for _, e := range x {
+			if d := nesting(e, max-1); d > deepest {
+				deepest = d
+			}
+		}
+	case map[string]interface{}:
+		for _, e := range x {
+			if d := nesting(e, max-1); d > deepest {
+				deepest = d
+			}
+		}
+	default:
+		return 0
+	}
+	return deepest + 1
+}
@@ -0,0 +1,139 @@
+package rfc6902
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Limits_Parse(t *testing.T) {
+	tests := []struct {
+		limits Limits
+		patch  string
+		limit  string
+		index  int
+	}{
+		{Limits{MaxOperations: 2}, `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/b"}]`, "", 0},
+		{Limits{MaxOperations: 1}, `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/b"}]`, "MaxOperations", 1},
+		// reading stops at the limit
+		{Limits{MaxOperations: 2}, `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/b"}, {"op": ` + strings.Repeat("[", 1e6), "MaxOperations", 2},
+		{Limits{MaxPointerDepth: 2}, `[{"op": "remove", "path": "/a/b"}]`, "", 0},
+		{Limits{MaxPointerDepth: 2}, `[{"op": "test", "path": "/a", "value": 1}, {"op": "remove", "path": "/a/b~1c/d"}]`, "MaxPointerDepth", 1},
+		{Limits{MaxPointerDepth: 2}, `[{"op": "move", "from": "/a/b/c", "path": "/d"}]`, "MaxPointerDepth", 0},
+		{Limits{MaxValueSize: 7}, `[{"op": "add", "path": "/a", "value": ["abc"]}]`, "", 0},
+		{Limits{MaxValueSize: 7}, `[{"op": "add", "path": "/a", "value": {"a": 1}}]`, "", 0},
+		{Limits{MaxValueSize: 7}, `[{"op": "add", "path": "/a", "value": ["abcd"]}]`, "MaxValueSize", 0},
+		{Limits{MaxValueSize: 7}, `[{"op": "add", "path": "/a", "value": {"a": 10}}]`, "MaxValueSize", 0},
+	}
+
+	for i, test := range tests {
+		ps := &Parser{Limits: &test.limits}
+		_, err := ps.Parse(strings.NewReader(test.patch))
+		var le *LimitError
+		switch {
+		case test.limit == "" && err != nil:
+			t.Errorf("%d: unexpected error %s", i, err)
+		case test.limit == "":
+		case !errors.As(err, &le):
+			t.Errorf("%d: (actual) %v != *LimitError (expected)", i, err)
+		case le.Limit != test.limit || le.Index != test.index:
+			t.Errorf("%d: (actual) %s at %d != %s at %d (expected)", i, le.Limit, le.Index, test.limit, test.index)
+		}
+	}
+}
+
+func Test_Limits_Apply(t *testing.T) {
+	copies := `[
+		{"op": "copy", "from": "/a", "path": "/b"},
+		{"op": "copy", "from": "/b", "path": "/a/b"},
+		{"op": "copy", "from": "/a", "path": "/b"}
+	]`
+	tests := []struct {
+		limits Limits
+		doc    string
+		patch  string
+		limit  string
+		index  int
+	}{
+		{Limits{MaxCopyBytes: 100}, `{"a": {"x": "0123456789"}}`, copies, "", 0},
+		{Limits{MaxCopyBytes: 50}, `{"a": {"x": "0123456789"}}`, copies, "MaxCopyBytes", 2},
+		{Limits{MaxCopyBytes: 10}, `{"a": "01234567"}`, `[{"op": "move", "from": "/a", "path": "/b"}]`, "", 0},
+		{Limits{MaxCopyBytes: 10}, `{"a": "0123456789"}`, `[{"op": "move", "from": "/a", "path": "/b"}]`, "MaxCopyBytes", 0},
+		{Limits{MaxDocumentSize: 20}, `{"a": "0123456789"}`, `[{"op": "remove", "path": "/a"}]`, "", 0},
+		{Limits{MaxDocumentSize: 10}, `{"a": "0123456789"}`, `[{"op": "remove", "path": "/a"}]`, "MaxDocumentSize", -1},
+		{Limits{MaxDocumentSize: 40}, `{"a": {"x": "0123456789"}}`, copies, "MaxDocumentSize", -1},
+		{Limits{MaxDocumentDepth: 2}, `{"a": [1]}`, `[{"op": "add", "path": "/b", "value": {}}]`, "", 0},
+		{Limits{MaxDocumentDepth: 1}, `{"a": [1]}`, `[{"op": "remove", "path": "/a"}]`, "MaxDocumentDepth", -1},
+		{Limits{MaxDocumentDepth: 2}, `{"a": [1]}`, `[{"op": "add", "path": "/a/0", "value": {}}]`, "MaxDocumentDepth", -1},
+		{Limits{MaxOperations: 1}, `{"a": [1, 2]}`, `[{"op": "remove", "path": "/a/0"}, {"op": "remove", "path": "/a/0"}]`, "MaxOperations", 1},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Failed parsing: %q. %s", i, test.patch, err)
+		}
+		_, err = p.ApplyWithOptions([]byte(test.doc), &ApplyOptions{Limits: &test.limits})
+		var le *LimitError
+		switch {
+		case test.limit == "" && err != nil:
+			t.Errorf("%d: unexpected error %s", i, err)
+		case test.limit == "":
+		case !errors.As(err, &le):
+			t.Errorf("%d: (actual) %v != *LimitError (expected)", i, err)
+		case le.Limit != test.limit || le.Index != test.index:
+			t.Errorf("%d: (actual) %s at %d != %s at %d (expected)", i, le.Limit, le.Index, test.limit, test.index)
+		}
+	}
+}
+
+func Test_Limits_Expand(t *testing.T) {
+	// every copy doubles the document
+	ops := make([]string, 40)
+	for i := range ops {
+		ops[i] = fmt.Sprintf(`{"op": "copy", "from": "/a", "path": "/a/m%d"}`, i)
+	}
+	copies := "[" + strings.Join(ops, ", ") + "]"
+	tests := []struct {
+		options ApplyOptions
+		patch   string
+		limit   string
+	}{
+		{ApplyOptions{Wildcards: true, Limits: &Limits{MaxCopyBytes: 1 << 20}}, copies, "MaxCopyBytes"},
+		{ApplyOptions{Keys: Keys{}, Limits: &Limits{MaxDocumentSize: 1 << 20}}, copies, "MaxDocumentSize"},
+		{ApplyOptions{Wildcards: true, Limits: &Limits{MaxOperations: 10}}, copies, "MaxOperations"},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Failed parsing: %s", i, err)
+		}
+		start := time.Now()
+		_, err = p.ApplyWithOptions([]byte(`{"a": {"x": 1}}`), &test.options)
+		var le *LimitError
+		if !errors.As(err, &le) || le.Limit != test.limit {
+			t.Errorf("%d: (actual) %v != %s (expected)", i, err, test.limit)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("%d: rejecting the patch took %s", i, d)
+		}
+	}
+}
+
+func Test_LimitError(t *testing.T) {
+	tests := []struct {
+		err      *LimitError
+		expected string
+	}{
+		{&LimitError{"MaxValueSize", 1024, 3}, "rfc6902: operation 3 exceeds MaxValueSize of 1024"},
+		{&LimitError{"MaxDocumentDepth", 8, -1}, "rfc6902: document exceeds MaxDocumentDepth of 8"},
+	}
+	for i, test := range tests {
+		if actual := test.err.Error(); actual != test.expected {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Severity grades a Finding.
+type Severity int
+
+const (
+	// SeverityInfo marks operations that may be intended but are worth a look.
+	SeverityInfo Severity = iota
+	// SeverityWarning marks operations without effect.
+	SeverityWarning
+	// SeverityError marks operations that fail on every document.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "error"
+}
+
+// MarshalText encodes the severity as "info", "warning" or "error".
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Finding is an issue Lint found with the operation at Index.
+type Finding struct {
+	Index    int      `json:"index"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d: %s: %s", f.Index, f.Severity, f.Message)
+}
+
+/*
+Lint reports issues with the operations of p, without a document:
+
+	error    a path that is not a JSON Pointer, "-" in the path of an
+	         operation other than add, move and copy or in from, a move into
+	         its own child
+	warning  a move to where it comes from, a value overwritten or removed
+	         by the next operation on its location, a replace of the value a
+	         test just asserted
+	info     a test immediately followed by a replace of its path, a remove
+	         immediately followed by an add at the same path
+
+Findings are ordered by Index.
+*/
+func Lint(p *Patcher) []Finding {
+	findings := make([]Finding, 0)
+	report := func(i int, s Severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{i, s, fmt.Sprintf(format, args...)})
+	}
+
+	for j, b := range p.ops {
+		locs := b.locations()
+		if locs == nil {
+			report(j, SeverityError, "invalid pointer")
+			continue
+		}
+		path := locs[0]
+		if last := len(path) - 1; last >= 0 && path[last] == "-" && b.Op != "add" && b.Op != "move" && b.Op != "copy" {
+			report(j, SeverityError, "%s of %s, past the end of an array", b.Op, b.Path)
+		}
+		if b.Op == "move" || b.Op == "copy" {
+			from := locs[1]
+			switch {
+			case len(from) > 0 && from[len(from)-1] == "-":
+				report(j, SeverityError, "%s from %s, past the end of an array", b.Op, b.From)
+			case b.Op == "copy":
+			case b.From == b.Path:
+				report(j, SeverityWarning, "move of %s to itself", b.Path)
+			case below(path, from):
+				report(j, SeverityError, "move of %s into its own child %s", b.From, b.Path)
+			}
+		}
+
+		i := lastConflict(p.ops[:j], b)
+		if i < 0 || p.ops[i].Path != b.Path || p.ops[i].locations() == nil {
+			continue
+		}
+		a := p.ops[i]
+		switch {
+		case (a.Op == "add" || a.Op == "replace") && b.Op == "replace":
+			report(i, SeverityWarning, "value written to %s is overwritten by %d", a.Path, j)
+		case (a.Op == "add" || a.Op == "replace") && b.Op == "remove":
+			report(i, SeverityWarning, "value written to %s is removed by %d", a.Path, j)
+		case a.Op == "test" && b.Op == "replace" && i == j-1:
+			if sameValue(a.Value, b.Value) {
+				report(j, SeverityWarning, "replace of %s with the value tested by %d", b.Path, i)
+			} else {
+				report(i, SeverityInfo, "test of %s is immediately replaced by %d", a.Path, j)
+			}
+		case a.Op == "remove" && b.Op == "add" && i == j-1:
+			report(i, SeverityInfo, "remove and add of %s could be a replace", a.Path)
+		}
+	}
+
+	// findings about earlier operations are appended late
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Index < findings[j].Index
+	})
+	return findings
+}
+
+/*
+Optimize returns a shorter patch with the same effect as p on the JSON
+document doc:
+
+  - replaces, and adds of object members, writing the value already there,
+    and moves to where they come from, are dropped
+  - a remove immediately followed by an add of the value removed becomes a
+    move
+  - the rest is squashed, see Squash
+
+Tests are kept, so the patch still fails where p fails on another document.
+p is returned unchanged if it would not apply to doc the same way.
+*/
+func Optimize(p *Patcher, doc []byte) (*Patcher, error) {
+	if len(doc) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var v interface{}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	expected, err := p.apply(clone(v))
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]Operation, 0, len(p.ops))
+	cur := clone(v)
+	for i := 0; i < len(p.ops); i++ {
+		op := p.ops[i]
+		next, err := op.apply(clone(cur))
+		if err != nil {
+			return nil, err
+		}
+		if unchanged(op, cur) {
+			continue
+		}
+		if op.Op == "remove" && i+1 < len(p.ops) {
+			n := p.ops[i+1]
+			ptr, _ := newJSONPointer(op.Path)
+			if n.Op == "add" && n.handler == nil && sameValue(n.Value, lookup(resolve(ptr, cur), cur)) {
+				move := Operation{Op: "move", From: op.Path, Path: n.Path}
+				if moved, err := move.apply(clone(cur)); err == nil {
+					ops = append(ops, move)
+					cur = moved
+					i++
+					continue
+				}
+			}
+		}
+		ops = append(ops, op)
+		cur = next
+	}
+
+	q := (&Patcher{ops: ops}).Squash()
+	if actual, err := q.apply(clone(v)); err != nil || !sameValue(actual, expected) || len(q.ops) > len(p.ops) {
+		return p, nil
+	}
+	return q, nil
+}
+
+// unchanged reports whether op leaves doc as it is and checks nothing.
+func unchanged(op Operation, doc interface{}) bool {
+	switch op.Op {
+	case "move":
+		return op.From == op.Path
+	case "replace", "add":
+		ptr, err := newJSONPointer(op.Path)
+		if err != nil || len(ptr) == 0 {
+			return false
+		}
+		if op.Op == "add" {
+			if _, ok := lookup(ptr[:len(ptr)-1], doc).(map[string]interface{}); !ok {
+				return false
+			}
+		}
+		old, ok := member(lookup(ptr[:len(ptr)-1], doc), ptr[len(ptr)-1])
+		return ok && sameValue(old, op.Value)
+	}
+	return false
+}
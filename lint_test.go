@@ -0,0 +1,98 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Lint(t *testing.T) {
+	tests := []struct {
+		patch    string
+		expected []string
+	}{
+		{`[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/b"}]`, nil},
+		{`[{"op": "replace", "path": "/a", "value": 1}, {"op": "add", "path": "/b", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`,
+			[]string{"0: warning: value written to /a is overwritten by 2"}},
+		{`[{"op": "replace", "path": "/a", "value": 1}, {"op": "remove", "path": "/a"}]`,
+			[]string{"0: warning: value written to /a is removed by 1"}},
+		{`[{"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 2}]`,
+			[]string{"0: info: test of /a is immediately replaced by 1"}},
+		{`[{"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 1}]`,
+			[]string{"1: warning: replace of /a with the value tested by 0"}},
+		{`[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/a", "value": 1}]`,
+			[]string{"0: info: remove and add of /a could be a replace"}},
+		{`[{"op": "remove", "path": "/a/-"}, {"op": "move", "from": "/a", "path": "/a/b"}, {"op": "move", "from": "/c", "path": "/c"}]`,
+			[]string{
+				"0: error: remove of /a/-, past the end of an array",
+				"1: error: move of /a into its own child /a/b",
+				"2: warning: move of /c to itself",
+			}},
+		{`[{"op": "remove", "path": "a"}]`, []string{"0: error: invalid pointer"}},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: ParsePatch failed: %s", i, err)
+		}
+		findings := Lint(p)
+		if len(findings) != len(test.expected) {
+			t.Errorf("%d: (actual) %v != %v (expected)", i, findings, test.expected)
+			continue
+		}
+		for j, f := range findings {
+			if f.String() != test.expected[j] {
+				t.Errorf("%d: (actual) %s != %s (expected)", i, f, test.expected[j])
+			}
+		}
+	}
+}
+
+func Test_Optimize(t *testing.T) {
+	doc := `{"a": 1, "b": [1, 2], "c": {"d": "x"}}`
+	tests := []struct {
+		patch, expected string
+	}{
+		{`[{"op": "replace", "path": "/a", "value": 1}, {"op": "add", "path": "/c/d", "value": "x"}]`, `[]`},
+		{`[{"op": "replace", "path": "/a", "value": 2}, {"op": "replace", "path": "/a", "value": 3}]`,
+			`[{"op": "replace", "path": "/a", "value": 3}]`},
+		{`[{"op": "remove", "path": "/c"}, {"op": "add", "path": "/e", "value": {"d": "x"}}]`,
+			`[{"op": "move", "from": "/c", "path": "/e"}]`},
+		{`[{"op": "remove", "path": "/b/0"}, {"op": "add", "path": "/b/-", "value": 1}]`,
+			`[{"op": "move", "from": "/b/0", "path": "/b/-"}]`},
+		// tests are kept
+		{`[{"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/a", "value": 1}]`,
+			`[{"op": "test", "path": "/a", "value": 1}]`},
+		// adding an equal element to an array inserts it
+		{`[{"op": "add", "path": "/b/0", "value": 1}]`,
+			`[{"op": "add", "path": "/b/0", "value": 1}]`},
+		{`[{"op": "move", "from": "/a", "path": "/a"}, {"op": "remove", "path": "/b"}]`,
+			`[{"op": "remove", "path": "/b"}]`},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: ParsePatch failed: %s", i, err)
+		}
+		q, err := Optimize(p, []byte(doc))
+		if err != nil {
+			t.Errorf("%d: Optimize failed: %s", i, err)
+			continue
+		}
+		actual, _ := q.MarshalJSON()
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+		want, _ := p.Apply([]byte(doc))
+		got, err := q.Apply([]byte(doc))
+		if err != nil || !jsonEqual(got, want) {
+			t.Errorf("%d: optimized patch gives %s, not %s", i, got, want)
+		}
+	}
+
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "remove", "path": "/x"}]`))
+	if _, err := Optimize(p, []byte(doc)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -2,21 +2,45 @@
 package rfc6902
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 )
 
-type op struct {
-	Op    string `json:"op"`
-	Path  string `json:"path"`
-	From  string `'json:"from"`
-	Value interface{}
+// ErrorTestFailed is returned when a test operation does not match.
+var ErrorTestFailed = errors.New("test condition failed")
+
+// Operation is a single operation of a patch (see section 4 Operations).
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	// IgnoreCase and Predicates are members of predicate operations, see
+	// Parser.Predicates.
+	IgnoreCase bool        `json:"ignore_case,omitempty"`
+	Predicates []Operation `json:"apply,omitempty"`
+
+	// handler applies a non-standard op
+	handler OperationHandler
+}
+
+// MarshalJSON encodes the operation.  The value of add, replace and test is
+// written even when it is null.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	type plain Operation
+	if o.Value != nil || (o.Op != "add" && o.Op != "replace" && o.Op != "test") {
+		return json.Marshal(plain(o))
+	}
+	return json.Marshal(struct {
+		plain
+		Value interface{} `json:"value"`
+	}{plain: plain(o)})
 }
 
-func (o *op) apply(v interface{}) (interface{}, error) {
+func (o *Operation) apply(v interface{}) (interface{}, error) {
 	ptr, err := newJSONPointer(o.Path)
 	if err != nil {
 		return nil, err
@@ -31,38 +55,68 @@ func (o *op) apply(v interface{}) (interface{}, error) {
 		return o.replace(ptr, v)
 	case "move":
 		return o.move(ptr, v)
+	case "copy":
+		return o.copy(ptr, v)
 	case "test":
 		return o.test(ptr, v)
 	default:
+		if o.handler != nil {
+			return o.applyHandler(ptr, v)
+		}
 		return nil, errors.New("rfc6902: unknown operation")
 	}
 }
 
-func (o *op) add(ptr jsonptr, v interface{}) (interface{}, error) {
+func (o *Operation) add(ptr jsonptr, v interface{}) (interface{}, error) {
+	// the value is copied, so the patch can be applied again
+	if len(ptr) == 0 {
+		return clone(o.Value), nil
+	}
 	p := patcher{ptr, v}
-	if err := p.setExistingValue(o.Value); err != nil {
+	if err := p.setExistingValue(clone(o.Value)); err != nil {
 		return nil, err
 	}
 	return p.jsonObject, nil
 }
 
-func (o *op) remove(ptr jsonptr, v interface{}) (interface{}, error) {
+func (o *Operation) remove(ptr jsonptr, v interface{}) (interface{}, error) {
 	p := patcher{ptr, v}
-	p.remove()
+	if len(ptr) == 0 || !p.exists() {
+		return nil, ErrorInvalidJSONPath
+	}
+	if err := p.remove(); err != nil {
+		return nil, err
+	}
 	return p.jsonObject, nil
 }
 
-func (o *op) replace(ptr jsonptr, v interface{}) (interface{}, error) {
+func (o *Operation) replace(ptr jsonptr, v interface{}) (interface{}, error) {
+	if len(ptr) == 0 {
+		return clone(o.Value), nil
+	}
 	p := patcher{ptr, v}
-	p.replace(o.Value)
+	if !p.exists() {
+		return nil, ErrorInvalidJSONPath
+	}
+	if err := p.replace(clone(o.Value)); err != nil {
+		return nil, err
+	}
 	return p.jsonObject, nil
 }
 
-func (o *op) move(ptr jsonptr, v interface{}) (interface{}, error) {
+func (o *Operation) move(ptr jsonptr, v interface{}) (interface{}, error) {
 	fromPtr, err := newJSONPointer(o.From)
 	if err != nil {
 		return nil, err
 	}
+	// the root cannot be removed, and a move into itself is an error
+	// (section 4.4 move) unless it moves nowhere
+	if len(fromPtr) == 0 {
+		if len(ptr) == 0 {
+			return v, nil
+		}
+		return nil, ErrorInvalidJSONPath
+	}
 	from := patcher{fromPtr, v}
 
 	fromObj, err := from.value()
@@ -81,37 +135,156 @@ func (o *op) move(ptr jsonptr, v interface{}) (interface{}, error) {
 	return p.jsonObject, nil
 }
 
-func (o *op) test(ptr jsonptr, v interface{}) (interface{}, error) {
+func (o *Operation) copy(ptr jsonptr, v interface{}) (interface{}, error) {
+	fromPtr, err := newJSONPointer(o.From)
+	if err != nil {
+		return nil, err
+	}
+	from := patcher{fromPtr, v}
+	fromObj, err := from.value()
+	if err != nil {
+		return nil, err
+	}
+	if len(ptr) == 0 {
+		return clone(fromObj), nil
+	}
+
+	p := patcher{ptr, v}
+	if err := p.setExistingValue(clone(fromObj)); err != nil {
+		return nil, err
+	}
+	return p.jsonObject, nil
+}
+
+func (o *Operation) test(ptr jsonptr, v interface{}) (interface{}, error) {
 	p := patcher{ptr, v}
 	v, err := p.value()
 	if err != nil {
 		return nil, err
 	}
-	if o.Value != v {
-		return nil, errors.New("test condition failed")
+	if !equalValue(o.Value, v, o.IgnoreCase) {
+		return nil, ErrorTestFailed
 	}
 	return p.jsonObject, nil
 }
 
 type Patcher struct {
-	ops []op
+	ops []Operation
+}
+
+// Parser reads patch documents.  The zero value accepts any RFC 6902 patch.
+type Parser struct {
+	// Schema, when set, rejects patches that write a value the schema does
+	// not allow at its target, remove a required property or append to
+	// something that is not an array.  Violations are returned as a
+	// *SchemaError.
+	Schema *Schema
+
+	// Extended enables the non-standard operations inc, str-append,
+	// array-union, array-remove-value and merge.  Otherwise they are
+	// rejected.
+	Extended bool
+
+	// Predicates enables the predicate operations defined, undefined, type,
+	// contains, starts, ends, matches, less, more, in, and, or and not.
+	Predicates bool
+
+	// Limits, when set, rejects patches with too many operations, pointers
+	// too deep or values too large as a *LimitError.
+	Limits *Limits
+
+	handlers map[string]OperationHandler
+}
+
+// members records the members of an operation found in a patch document, as
+// a null value or an empty from decodes like a missing one.
+type members struct {
+	Value json.RawMessage `json:"value"`
+	From  json.RawMessage `json:"from"`
+	Apply []members       `json:"apply"`
+}
+
+// has reports whether the member name was found.  A null from is missing.
+func (m members) has(name string) bool {
+	switch name {
+	case "value":
+		return m.Value != nil
+	case "from":
+		return m.From != nil && string(m.From) != "null"
+	}
+	return false
+}
+
+// decode reads the operations of a patch document from r and the members
+// each has, stopping at Limits.MaxOperations.
+func (ps *Parser) decode(r io.Reader) (p *Patcher, found []members, err error) {
+	dec := json.NewDecoder(r)
+	t, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	p = &Patcher{ops: make([]Operation, 0)}
+	if t != json.Delim('[') && t != nil {
+		return nil, nil, errors.New("rfc6902: patch document is not an array")
+	}
+	for t != nil && dec.More() {
+		if ps.Limits != nil && ps.Limits.MaxOperations > 0 && len(p.ops) == ps.Limits.MaxOperations {
+			return nil, nil, &LimitError{"MaxOperations", ps.Limits.MaxOperations, len(p.ops)}
+		}
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		var op Operation
+		if err = json.Unmarshal(raw, &op); err != nil {
+			return nil, nil, err
+		}
+		var m members
+		if err = json.Unmarshal(raw, &m); err != nil {
+			return nil, nil, err
+		}
+		p.ops = append(p.ops, op)
+		found = append(found, m)
+	}
+	if t != nil {
+		if _, err = dec.Token(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err = dec.Token(); err == nil {
+		return nil, nil, errors.New("rfc6902: data after the patch document")
+	} else if err != io.EOF {
+		return nil, nil, err
+	}
+	return p, found, nil
 }
 
 func ParsePatch(r io.Reader) (*Patcher, error) {
+	return new(Parser).Parse(r)
+}
+
+// Parse reads a patch document from r.
+func (ps *Parser) Parse(r io.Reader) (*Patcher, error) {
 	if r == nil {
 		return nil, errors.New("reader is nil")
 	}
 
-	b := new(bytes.Buffer)
-	b.ReadFrom(r)
-	p := new(Patcher)
-	p.ops = make([]op, 0)
-	err := json.Unmarshal(b.Bytes(), &p.ops)
+	p, found, err := ps.decode(r)
 	if err != nil {
 		return nil, err
 	}
+	if ps.Limits != nil {
+		if err := ps.Limits.checkPatch(p); err != nil {
+			return nil, err
+		}
+	}
 
 	for pos, op := range p.ops {
+		if !ps.Predicates {
+			// members of other operations are ignored (section 4 Operations)
+			op.IgnoreCase, op.Predicates = false, nil
+			p.ops[pos] = op
+		}
 		if len(op.Op) == 0 {
 			return nil, fmt.Errorf("rfc6902: missing op at %d (section 4 Operations)", pos)
 		}
@@ -120,37 +293,117 @@ func ParsePatch(r io.Reader) (*Patcher, error) {
 		}
 		switch op.Op {
 		case "add":
-			if op.Value == nil {
+			if !found[pos].has("value") {
 				return nil, fmt.Errorf("rfc6902: missing value for add op (section 4.1 add)")
 			}
+		case "move":
+			if !found[pos].has("from") {
+				return nil, fmt.Errorf("rfc6902: missing from for move op (section 4.4 move)")
+			}
+		case "copy":
+			if !found[pos].has("from") {
+				return nil, fmt.Errorf("rfc6902: missing from for copy op (section 4.5 copy)")
+			}
+		case "remove", "replace", "test":
+		default:
+			h := ps.handler(op.Op)
+			if h == nil {
+				_, ext := extended[op.Op]
+				_, pred := predicates[op.Op]
+				if ext || pred {
+					return nil, fmt.Errorf("rfc6902: non-standard op %q at %d", op.Op, pos)
+				}
+				break
+			}
+			if err := checkMembers(h, op, found[pos], pos); err != nil {
+				return nil, err
+			}
+			if c, ok := h.(checker); ok {
+				if err := c.check(op, found[pos]); err != nil {
+					return nil, fmt.Errorf("rfc6902: %s at %d", err, pos)
+				}
+			}
+			p.ops[pos].handler = h
 		}
 	}
 
+	if ps.Schema != nil {
+		if err := ps.Schema.checkPatch(p); err != nil {
+			return nil, err
+		}
+	}
 	return p, nil
 }
 
-func (p *Patcher) Apply(b []byte) ([]byte, error) {
-	if len(b) <= 0 {
-		return nil, errors.New("rfc6902: empty JSON document")
+// MarshalJSON encodes the patch as a JSON Patch document.
+func (p *Patcher) MarshalJSON() ([]byte, error) {
+	if p.ops == nil {
+		return []byte("[]"), nil
 	}
-	var v interface{}
-	err := json.Unmarshal(b, &v)
+	return json.Marshal(p.ops)
+}
+
+func (p *Patcher) Apply(b []byte) ([]byte, error) {
+	return p.ApplyWithOptions(b, nil)
+}
+
+// ApplyWithOptions applies the patch like Apply, customised by o.
+func (p *Patcher) ApplyWithOptions(b []byte, o *ApplyOptions) ([]byte, error) {
+	c := o.codec()
+	v, err := c.Decode(b)
 	if err != nil {
 		return nil, err
 	}
-	v, err = p.apply(v)
+	v, err = p.applyWith(v, o)
 	if err != nil {
 		return nil, err
 	}
-	return json.Marshal(v)
+	return c.Encode(v)
+}
+
+func (p *Patcher) apply(v interface{}) (interface{}, error) {
+	return p.applyWith(v, nil)
 }
 
-func (p *Patcher) apply(v interface{}) (result interface{}, err error) {
+func (p *Patcher) applyWith(v interface{}, o *ApplyOptions) (result interface{}, err error) {
+	if o != nil {
+		// keys and wildcards are resolved first, so the checks see the
+		// locations the operations change
+		if o.Wildcards || o.Keys != nil {
+			if o.Limits != nil {
+				if err = o.Limits.checkPatch(p); err != nil {
+					return
+				}
+			}
+			if p, err = p.expand(v, o); err != nil {
+				return
+			}
+		}
+		if err = o.check(p, v); err != nil {
+			return
+		}
+	}
 	result = v
-	for _, op := range p.ops {
-		if result, err = op.apply(result); err != nil {
+	copied := 0
+	for i, op := range p.ops {
+		if o != nil && o.Limits != nil {
+			if copied, err = o.Limits.copied(i, op, result, copied); err != nil {
+				return
+			}
+		}
+		if o != nil {
+			result, err = o.applyOp(i, op, result)
+		} else {
+			result, err = op.apply(result)
+		}
+		if err != nil {
 			return
 		}
 	}
+	if o != nil {
+		if err = o.verify(p, result); err != nil {
+			return nil, err
+		}
+	}
 	return
 }
@@ -24,6 +24,14 @@ func Test_ParsePatch_EmptyReader(t *testing.T) {
 
 }
 
+func Test_ParsePatch_Malformed(t *testing.T) {
+	for _, doc := range []string{`{"op": "remove", "path": "/a"}`, `[{"op": "remove", "path": "/a"}] []`, `[{"op": "remove", "path": "/a"}`, `[1]`} {
+		if _, err := ParsePatch(strings.NewReader(doc)); err == nil {
+			t.Errorf("For doc: %q expected an error", doc)
+		}
+	}
+}
+
 func Test_ParsePatch_MissingRequiredElements(t *testing.T) {
 	tests := []struct {
 		invalidPatchDoc string
@@ -32,6 +40,8 @@ func Test_ParsePatch_MissingRequiredElements(t *testing.T) {
 		{"[{\"path\": \"/a/b/c/\"}]", fmt.Errorf("rfc6902: missing op at 0 (section 4 Operations)")},
 		{"[{\"op\": \"add\"}]", fmt.Errorf("rfc6902: missing path at 0 (section 4 Operations)")},
 		{"[{\"op\": \"add\", \"path\": \"/a/b/c\"}]", fmt.Errorf("rfc6902: missing value for add op (section 4.1 add)")},
+		{"[{\"op\": \"move\", \"path\": \"/a\"}]", fmt.Errorf("rfc6902: missing from for move op (section 4.4 move)")},
+		{"[{\"op\": \"copy\", \"path\": \"/a\", \"from\": null}]", fmt.Errorf("rfc6902: missing from for copy op (section 4.5 copy)")},
 	}
 
 	for _, test := range tests {
@@ -42,6 +52,40 @@ func Test_ParsePatch_MissingRequiredElements(t *testing.T) {
 	}
 }
 
+func Test_Operation_MoveRoot(t *testing.T) {
+	p, err := ParsePatch(strings.NewReader(`[{"op": "move", "from": "", "path": "/x"}]`))
+	if err != nil {
+		t.Fatalf("ParsePatch failed: %s", err)
+	}
+	if _, err := p.Apply([]byte(`{"a": 1}`)); err != ErrorInvalidJSONPath {
+		t.Errorf("(actual) %v != %v (expected)", err, ErrorInvalidJSONPath)
+	}
+
+	op := Operation{Op: "move", From: "", Path: ""}
+	actual, err := op.apply(map[string]interface{}{"a": 1.0})
+	if err != nil || !reflect.DeepEqual(actual, map[string]interface{}{"a": 1.0}) {
+		t.Errorf("(actual) %v, %v != unchanged document (expected)", actual, err)
+	}
+	op = Operation{Op: "remove", Path: ""}
+	if _, err := op.apply(1.0); err != ErrorInvalidJSONPath {
+		t.Errorf("(actual) %v != %v (expected)", err, ErrorInvalidJSONPath)
+	}
+}
+
+func Test_ParsePatch_RootFrom(t *testing.T) {
+	p, err := ParsePatch(strings.NewReader(`[{"op": "copy", "from": "", "path": "/a"}]`))
+	if err != nil {
+		t.Fatalf("ParsePatch failed: %s", err)
+	}
+	actual, err := p.Apply([]byte(`{"b": 1}`))
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if !jsonEqual(actual, []byte(`{"a": {"b": 1}, "b": 1}`)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, `{"a": {"b": 1}, "b": 1}`)
+	}
+}
+
 func Test_OperationAdd(t *testing.T) {
 
 	patch := `[{ "op": "add", "path": "/baz", "value": "qux" }]`
@@ -72,6 +116,43 @@ func Test_PatchApply_Empty(t *testing.T) {
 	}
 }
 
+func Test_PatchApply_Reuse(t *testing.T) {
+	p, err := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/c", "value": {"n": 1}}, {"op": "remove", "path": "/c/n"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []*ApplyOptions{nil, nil, {Wildcards: true}, {Wildcards: true}}
+	for i, o := range tests {
+		actual, err := p.ApplyWithOptions([]byte(`{}`), o)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if expected := `{"c": {}}`; !jsonEqual(actual, []byte(expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, expected)
+		}
+	}
+}
+
+func Test_Patcher_MarshalJSON_Nulls(t *testing.T) {
+	a, b := `{"a": 1, "l": [1]}`, `{"a": null, "b": null, "l": [1, null]}`
+	p, err := CreatePatch([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := p.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := ParsePatch(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Failed parsing: %s. %s", encoded, err)
+	}
+	actual, err := q.Apply([]byte(a))
+	if err != nil || !jsonEqual(actual, []byte(b)) {
+		t.Errorf("(actual) %s != %s (expected): %v", actual, b, err)
+	}
+}
+
 func Test_RFC6902_AppendixMutators(t *testing.T) {
 
 	tests := []struct {
@@ -131,6 +212,12 @@ func Test_RFC6902_AppendixMutators(t *testing.T) {
 			patch:    `[ { "op": "move", "from": "/foo/1", "path": "/foo/3" } ]`,
 			expect:   `{ "foo": [ "all", "cows", "eat", "grass" ] }`,
 		},
+		{
+			rfcTitle: "Extra Credit: Copying a Value",
+			target:   `{ "foo": { "bar": "baz" }, "qux": [ 1 ] }`,
+			patch:    `[ { "op": "copy", "from": "/foo", "path": "/qux/0" }, { "op": "add", "path": "/foo/waldo", "value": "fred" } ]`,
+			expect:   `{ "foo": { "bar": "baz", "waldo": "fred" }, "qux": [ { "bar": "baz" }, 1 ] }`,
+		},
 		{
 			rfcTitle: "A.10. Adding a Nested Member Object",
 			target:   `{ "foo": "bar" }`,
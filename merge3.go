@@ -0,0 +1,182 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// ErrorMergeConflict is returned by FailOnConflict.
+var ErrorMergeConflict = errors.New("rfc6902: conflicting changes")
+
+// ConflictKind classifies a Conflict.
+type ConflictKind int
+
+const (
+	// ConflictChanged means both sides changed the same location differently.
+	ConflictChanged ConflictKind = iota
+	// ConflictRemoved means one side changed a location below a location the
+	// other side removed.
+	ConflictRemoved
+)
+
+// Resolution records which side of a Conflict was kept.
+type Resolution int
+
+const (
+	// Unresolved conflicts are left out of the merged patch.
+	Unresolved Resolution = iota
+	TakeOurs
+	TakeTheirs
+)
+
+// Conflict describes changes of both sides that cannot be combined.
+type Conflict struct {
+	Kind ConflictKind
+	// Path points to the conflicting location in the base document.  For
+	// ConflictRemoved it is the changed location below Removed.
+	Path string
+	// Removed points to the location removed by one side.
+	Removed string
+	// Base, Ours and Theirs hold the value at Path in each document, nil when
+	// it is absent.
+	Base, Ours, Theirs interface{}
+	Resolution         Resolution
+}
+
+// A Resolver decides a conflict.  Returning an error aborts the merge.
+type Resolver func(c *Conflict) (Resolution, error)
+
+// PreferOurs resolves every conflict in favour of our changes.
+func PreferOurs(c *Conflict) (Resolution, error) {
+	return TakeOurs, nil
+}
+
+// PreferTheirs resolves every conflict in favour of their changes.
+func PreferTheirs(c *Conflict) (Resolution, error) {
+	return TakeTheirs, nil
+}
+
+// FailOnConflict aborts the merge on the first conflict.
+func FailOnConflict(c *Conflict) (Resolution, error) {
+	return Unresolved, ErrorMergeConflict
+}
+
+// A Merger combines concurrent edits of a document.  Conflicts are passed to
+// Resolve, when set, and left unresolved otherwise.
+type Merger struct {
+	Resolve Resolver
+}
+
+// Merge3 merges ours and theirs, both derived from base, leaving conflicts
+// unresolved.
+func Merge3(base, ours, theirs []byte) (*Patcher, []Conflict, error) {
+	return new(Merger).Merge3(base, ours, theirs)
+}
+
+/*
+Merge3 diffs ours and theirs against base and returns a patch applying both
+sets of changes to base, together with every conflict found.
+
+Identical changes made on both sides are applied once and insertions at the
+same array index are both kept, ours first.  Any other pair of changes where
+one location is the same as or below the other is a conflict; only the side
+chosen by the resolver is applied.
+*/
+func (m *Merger) Merge3(base, ours, theirs []byte) (*Patcher, []Conflict, error) {
+	docs := make([]interface{}, 3)
+	for i, b := range [][]byte{base, ours, theirs} {
+		if len(b) <= 0 {
+			return nil, nil, errors.New("rfc6902: empty JSON document")
+		}
+		if err := json.Unmarshal(b, &docs[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// array edits from diff use indices into base, so paths are comparable
+	os := diff(jsonptr{}, docs[0], docs[1])
+	ts := diff(jsonptr{}, docs[0], docs[2])
+	dropOurs := make([]bool, len(os))
+	dropTheirs := make([]bool, len(ts))
+
+	var conflicts []Conflict
+	for i, x := range os {
+		xp, _ := newJSONPointer(x.Path)
+		for j, y := range ts {
+			if dropOurs[i] {
+				break
+			}
+			if dropTheirs[j] {
+				continue
+			}
+			yp, _ := newJSONPointer(y.Path)
+			n := commonPrefix(xp, yp)
+			if n != len(xp) && n != len(yp) {
+				continue
+			}
+			if x.Op == y.Op && x.Path == y.Path && reflect.DeepEqual(x.Value, y.Value) {
+				dropTheirs[j] = true
+				continue
+			}
+			if x.Op == "add" && y.Op == "add" && x.Path == y.Path && isIndex(xp[len(xp)-1]) {
+				continue
+			}
+
+			c := Conflict{Kind: ConflictChanged, Path: x.Path}
+			switch {
+			case len(xp) < len(yp) && x.Op == "remove":
+				c.Kind, c.Path, c.Removed = ConflictRemoved, y.Path, x.Path
+			case len(yp) < len(xp) && y.Op == "remove":
+				c.Kind, c.Removed = ConflictRemoved, y.Path
+			case len(yp) < len(xp):
+				c.Path = y.Path
+			}
+			ptr, _ := newJSONPointer(c.Path)
+			c.Base = lookup(ptr, docs[0])
+			c.Ours = lookup(ptr, docs[1])
+			c.Theirs = lookup(ptr, docs[2])
+
+			if m.Resolve != nil {
+				r, err := m.Resolve(&c)
+				if err != nil {
+					return nil, append(conflicts, c), err
+				}
+				c.Resolution = r
+			}
+			switch c.Resolution {
+			case TakeOurs:
+				dropTheirs[j] = true
+			case TakeTheirs:
+				dropOurs[i] = true
+			default:
+				dropOurs[i], dropTheirs[j] = true, true
+			}
+			conflicts = append(conflicts, c)
+		}
+	}
+
+	a, b := &Patcher{ops: keep(os, dropOurs)}, &Patcher{ops: keep(ts, dropTheirs)}
+	_, b2 := Transform(a, b)
+	return &Patcher{ops: append(a.ops, b2.ops...)}, conflicts, nil
+}
+
+func keep(ops []Operation, drop []bool) []Operation {
+	kept := make([]Operation, 0, len(ops))
+	for i, o := range ops {
+		if !drop[i] {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+// lookup returns the value at ptr, or nil if there is none.
+func lookup(ptr jsonptr, doc interface{}) interface{} {
+	p := patcher{ptr, doc}
+	v, err := p.value()
+	if err != nil {
+		return nil
+	}
+	return v
+}
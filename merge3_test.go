@@ -0,0 +1,96 @@
+package rfc6902
+
+import (
+	"testing"
+)
+
+func Test_Merge3(t *testing.T) {
+	tests := []struct {
+		base, ours, theirs, expected string
+		conflicts                    []Conflict
+	}{
+		{
+			base:     `{"a": 1, "b": 1, "l": [1, 2, 3]}`,
+			ours:     `{"a": 2, "b": 1, "l": [0, 1, 2, 3]}`,
+			theirs:   `{"a": 1, "b": 2, "l": [1, 3, 4]}`,
+			expected: `{"a": 2, "b": 2, "l": [0, 1, 3, 4]}`,
+		},
+		{
+			base:     `{"a": 1, "l": ["x"]}`,
+			ours:     `{"a": 2, "l": ["x", "o"]}`,
+			theirs:   `{"a": 2, "l": ["x", "t"]}`,
+			expected: `{"a": 2, "l": ["x", "o", "t"]}`,
+		},
+		{
+			base:      `{"a": 1, "b": 1}`,
+			ours:      `{"a": 2, "b": 2}`,
+			theirs:    `{"a": 3, "b": 1}`,
+			expected:  `{"a": 1, "b": 2}`,
+			conflicts: []Conflict{{Kind: ConflictChanged, Path: "/a", Base: 1.0, Ours: 2.0, Theirs: 3.0}},
+		},
+		{
+			base:      `{"a": {"b": 1, "c": 1}, "d": 1}`,
+			ours:      `{"d": 1}`,
+			theirs:    `{"a": {"b": 2, "c": 1}, "d": 2}`,
+			expected:  `{"a": {"b": 1, "c": 1}, "d": 2}`,
+			conflicts: []Conflict{{Kind: ConflictRemoved, Path: "/a/b", Removed: "/a", Base: 1.0, Theirs: 2.0}},
+		},
+	}
+
+	for i, test := range tests {
+		p, conflicts, err := Merge3([]byte(test.base), []byte(test.ours), []byte(test.theirs))
+		if err != nil {
+			t.Fatalf("%d: Merge3 failed: %s", i, err)
+		}
+		actual, err := p.Apply([]byte(test.base))
+		if err != nil {
+			t.Fatalf("%d: Unable to apply merged patch: %s", i, err)
+		}
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+		if len(conflicts) != len(test.conflicts) {
+			t.Fatalf("%d: conflicts (actual) %#v != %#v (expected)", i, conflicts, test.conflicts)
+		}
+		for j, c := range conflicts {
+			if c != test.conflicts[j] {
+				t.Errorf("%d: conflict (actual) %#v != %#v (expected)", i, c, test.conflicts[j])
+			}
+		}
+	}
+}
+
+func Test_Merge3_Resolve(t *testing.T) {
+	base := `{"a": {"b": 1}, "c": 1}`
+	ours := `{"c": 2}`
+	theirs := `{"a": {"b": 2}, "c": 3}`
+
+	tests := []struct {
+		resolve  Resolver
+		expected string
+	}{
+		{PreferOurs, `{"c": 2}`},
+		{PreferTheirs, `{"a": {"b": 2}, "c": 3}`},
+	}
+	for i, test := range tests {
+		p, conflicts, err := (&Merger{Resolve: test.resolve}).Merge3([]byte(base), []byte(ours), []byte(theirs))
+		if err != nil {
+			t.Fatalf("%d: Merge3 failed: %s", i, err)
+		}
+		if len(conflicts) != 2 {
+			t.Errorf("%d: (actual) %d != 2 (expected) conflicts", i, len(conflicts))
+		}
+		actual, _ := p.Apply([]byte(base))
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+
+	_, conflicts, err := (&Merger{Resolve: FailOnConflict}).Merge3([]byte(base), []byte(ours), []byte(theirs))
+	if err != ErrorMergeConflict {
+		t.Errorf("FailOnConflict: (actual) %v != %v (expected)", err, ErrorMergeConflict)
+	}
+	if len(conflicts) != 1 {
+		t.Errorf("FailOnConflict: (actual) %d != 1 (expected) conflicts", len(conflicts))
+	}
+}
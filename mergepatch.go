@@ -0,0 +1,41 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to the JSON document doc.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	if len(doc) <= 0 || len(patch) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+// mergePatch implements section 2 of RFC 7396.  target is modified in place.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+		} else {
+			t[name] = mergePatch(t[name], value)
+		}
+	}
+	return t
+}
@@ -0,0 +1,268 @@
+package rfc6902
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errMsgpackTruncated = errors.New("rfc6902: truncated MessagePack document")
+
+/*
+msgpackCodec reads and writes MessagePack.
+
+Integers and floats are read as float64 and integral values are written back
+in the smallest integer format.  Integers a float64 cannot hold exactly are
+rejected rather than rounded.  Binary strings are read as []byte.
+Extension types are not supported.
+*/
+type msgpackCodec struct{}
+
+func (msgpackCodec) Decode(b []byte) (interface{}, error) {
+	if len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty MessagePack document")
+	}
+	d := &msgpackDecoder{b: b}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(b) {
+		return nil, errors.New("rfc6902: trailing data after MessagePack document")
+	}
+	return v, nil
+}
+
+func (msgpackCodec) MediaType() string { return "application/msgpack" }
+
+func (msgpackCodec) Encode(v interface{}) ([]byte, error) {
+	return msgpackAppend(nil, v)
+}
+
+type msgpackDecoder struct {
+	b   []byte
+	off int
+}
+
+// uint reads an n byte big-endian unsigned integer.
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	if d.off+n > len(d.b) {
+		return 0, errMsgpackTruncated
+	}
+	var u uint64
+	for _, b := range d.b[d.off : d.off+n] {
+		u = u<<8 | uint64(b)
+	}
+	d.off += n
+	return u, nil
+}
+
+// bytes reads n bytes.
+func (d *msgpackDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.b)-d.off) {
+		return nil, errMsgpackTruncated
+	}
+	s := make([]byte, n)
+	copy(s, d.b[d.off:])
+	d.off += int(n)
+	return s, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, errors.New("rfc6902: MessagePack document nested too deeply")
+	}
+	if d.off >= len(d.b) {
+		return nil, errMsgpackTruncated
+	}
+	c := d.b[d.off]
+	d.off++
+
+	switch {
+	case c <= 0x7f:
+		return float64(c), nil
+	case c >= 0xe0:
+		return float64(int8(c)), nil
+	case c <= 0x8f:
+		return d.mapOf(uint64(c&0x0f), depth)
+	case c <= 0x9f:
+		return d.arrayOf(uint64(c&0x0f), depth)
+	case c <= 0xbf:
+		s, err := d.bytes(uint64(c & 0x1f))
+		return string(s), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.bytes(n)
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return fromUint(u)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		u, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend
+		shift := 64 - 8*n
+		return fromInt(int64(u<<shift) >> shift)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.bytes(n)
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayOf(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapOf(n, depth)
+	}
+	return nil, fmt.Errorf("rfc6902: unsupported MessagePack type 0x%02x", c)
+}
+
+func (d *msgpackDecoder) arrayOf(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.b)-d.off) {
+		return nil, errMsgpackTruncated
+	}
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (d *msgpackDecoder) mapOf(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.b)-d.off) {
+		return nil, errMsgpackTruncated
+	}
+	m := make(map[string]interface{}, n)
+	for i := uint64(0); i < n; i++ {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, err := mapKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if m[key], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// msgpackHead appends a length prefix, using fix, or code followed by a
+// 1, 2 or 4 byte length as far as the format has them.
+func msgpackHead(b []byte, n int, fix byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint8 && code8 != 0:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// msgpackAppend appends the encoding of v to b.  Map keys are written in
+// sorted order.
+func msgpackAppend(b []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if x {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case float64:
+		if !integral(x) {
+			return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(x)), nil
+		}
+		if x >= 0 {
+			u := uint64(x)
+			switch {
+			case u <= 0x7f:
+				return append(b, byte(u)), nil
+			case u <= math.MaxUint8:
+				return append(b, 0xcc, byte(u)), nil
+			case u <= math.MaxUint16:
+				return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(u)), nil
+			case u <= math.MaxUint32:
+				return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(u)), nil
+			}
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), u), nil
+		}
+		i := int64(x)
+		switch {
+		case i >= -32:
+			return append(b, byte(i)), nil
+		case i >= math.MinInt8:
+			return append(b, 0xd0, byte(i)), nil
+		case i >= math.MinInt16:
+			return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i)), nil
+		case i >= math.MinInt32:
+			return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i)), nil
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i)), nil
+	case string:
+		return append(msgpackHead(b, len(x), 0xa0, 31, 0xd9, 0xda, 0xdb), x...), nil
+	case []byte:
+		return append(msgpackHead(b, len(x), 0, -1, 0xc4, 0xc5, 0xc6), x...), nil
+	case []interface{}:
+		b = msgpackHead(b, len(x), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, e := range x {
+			if b, err = msgpackAppend(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		b = msgpackHead(b, len(x), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range sortedKeys(x) {
+			b = append(msgpackHead(b, len(k), 0xa0, 31, 0xd9, 0xda, 0xdb), k...)
+			if b, err = msgpackAppend(b, x[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("rfc6902: cannot encode %T as MessagePack", v)
+}
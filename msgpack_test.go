@@ -0,0 +1,107 @@
+package rfc6902
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_MessagePack_RoundTrip(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected interface{}
+	}{
+		{"00", 0.0},
+		{"7f", 127.0},
+		{"cc80", 128.0},
+		{"cd0100", 256.0},
+		{"ce00010000", 65536.0},
+		{"cf0000000100000000", 4294967296.0},
+		{"ff", -1.0},
+		{"e0", -32.0},
+		{"d0df", -33.0},
+		{"d1ff7f", -129.0},
+		{"d2ffff7fff", -32769.0},
+		{"d3ffffffff7fffffff", -2147483649.0},
+		{"cb3ff8000000000000", 1.5},
+		{"c0", nil},
+		{"c2", false},
+		{"c3", true},
+		{"a3616263", "abc"},
+		{"d920" + strings.Repeat("61", 32), strings.Repeat("a", 32)},
+		{"c4020102", []byte{1, 2}},
+		{"92019100", []interface{}{1.0, []interface{}{0.0}}},
+		{"82a16101a162c0", map[string]interface{}{"a": 1.0, "b": nil}},
+	}
+
+	for _, test := range tests {
+		actual, err := MessagePack.Decode(unhex(test.in))
+		if err != nil {
+			t.Errorf("%s: Decode failed: %s", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: (actual) %#v != %#v (expected)", test.in, actual, test.expected)
+		}
+		b, err := MessagePack.Encode(actual)
+		if err != nil {
+			t.Errorf("%s: Encode failed: %s", test.in, err)
+			continue
+		}
+		if hex.EncodeToString(b) != test.in {
+			t.Errorf("%s: (actual) %x != %s (expected)", test.in, b, test.in)
+		}
+	}
+}
+
+func Test_MessagePack_Decode(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected interface{}
+	}{
+		{"ca3fc00000", 1.5},
+		{"da0001" + "61", "a"},
+		{"c500020102", []byte{1, 2}},
+		{"dc00020102", []interface{}{1.0, 2.0}},
+		{"81017b", map[string]interface{}{"1": 123.0}},
+		{"81c3c0", map[string]interface{}{"true": nil}},
+		{"d3ffe0000000000000", -9007199254740992.0},
+	}
+	for _, test := range tests {
+		actual, err := MessagePack.Decode(unhex(test.in))
+		if err != nil {
+			t.Errorf("%s: Decode failed: %s", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: (actual) %#v != %#v (expected)", test.in, actual, test.expected)
+		}
+	}
+
+	for _, in := range []string{"", "cc", "cd00", "a2", "92", "81", "c1", "d401", "8190c0", "0000", "ddffffffff",
+		// integers a float64 cannot hold exactly
+		"cf0020000000000001", "d3ffdfffffffffffff"} {
+		if _, err := MessagePack.Decode(unhex(in)); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func Test_MessagePack_Apply(t *testing.T) {
+	// {"data": bin 0x01 0x02, 7: [1]}
+	doc := unhex("82a464617461c40201020791" + "01")
+	p, _ := ParsePatch(strings.NewReader(`[
+		{"op": "test", "path": "/data", "value": "\u0001\u0002"},
+		{"op": "add", "path": "/7/-", "value": -200}
+	]`))
+	actual, err := p.ApplyWithOptions(doc, &ApplyOptions{Codec: MessagePack})
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	v, _ := MessagePack.Decode(actual)
+	expected := map[string]interface{}{"data": []byte{1, 2}, "7": []interface{}{1.0, -200.0}}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("(actual) %#v != %#v (expected)", v, expected)
+	}
+}
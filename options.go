@@ -0,0 +1,115 @@
+package rfc6902
+
+// ApplyOptions customises Patcher.ApplyWithOptions.  Hooks receive the live
+// document and must not modify it.
+type ApplyOptions struct {
+	// Codec reads and writes the document, JSON when nil.
+	Codec Codec
+
+	// Wildcards enables wildcard and JSONPath paths, see Patcher.Expand.
+	// The other options see the expanded operations.
+	Wildcards bool
+
+	// Keys, when not nil, enables keyed array addressing.  Keyed tokens are
+	// replaced by indices before the patch is checked or applied, so Policy
+	// and the hooks see the elements addressed.
+	Keys Keys
+
+	// Policy, when set, must allow every operation before any is applied.
+	Policy *Policy
+
+	// Schema, when set, must accept the patched document.  Violations are
+	// returned as a *SchemaError.
+	Schema *Schema
+
+	// Limits, when set, bounds the patch, the document before and after it
+	// is patched and the bytes copied.  See Limits.
+	Limits *Limits
+
+	// BeforeOp is called before the operation at index is applied to doc.
+	// Returning an error vetoes the operation and fails the patch.
+	BeforeOp func(index int, op Operation, doc interface{}) error
+
+	// AfterOp is called after the operation at index was applied with the
+	// value previously found at its target (see Result.Old) and the value
+	// now found there.  Returning an error fails the patch.
+	AfterOp func(index int, op Operation, oldValue, newValue interface{}) error
+}
+
+func (o *ApplyOptions) codec() Codec {
+	if o == nil || o.Codec == nil {
+		return JSON
+	}
+	return o.Codec
+}
+
+// check is called once before the operations of p are applied to doc.
+func (o *ApplyOptions) check(p *Patcher, doc interface{}) error {
+	if o.Limits != nil {
+		if err := o.Limits.checkPatch(p); err != nil {
+			return err
+		}
+		if err := o.Limits.checkDocument(doc); err != nil {
+			return err
+		}
+	}
+	if o.Policy != nil {
+		return o.Policy.Check(p)
+	}
+	return nil
+}
+
+// verify is called with the patched document before it is returned.
+func (o *ApplyOptions) verify(p *Patcher, doc interface{}) error {
+	if o.Limits != nil {
+		if err := o.Limits.checkDocument(doc); err != nil {
+			return err
+		}
+	}
+	if o.Schema != nil {
+		return o.Schema.check(doc, p)
+	}
+	return nil
+}
+
+func (o *ApplyOptions) applyOp(index int, op Operation, doc interface{}) (interface{}, error) {
+	if o.BeforeOp != nil {
+		if err := o.BeforeOp(index, op, doc); err != nil {
+			return nil, err
+		}
+	}
+	if o.AfterOp == nil {
+		return op.apply(doc)
+	}
+
+	ptr, err := newJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	target := resolve(ptr, doc)
+	oldValue := previous(op.Op, target, doc)
+	if oldValue != nil {
+		// the operation may modify it in place
+		oldValue = clone(oldValue)
+	}
+
+	doc, err = op.apply(doc)
+	if err != nil {
+		return nil, err
+	}
+	var newValue interface{}
+	if n := len(ptr); n > 0 && ptr[n-1] == "-" {
+		// an appended value is the last element, also when a move removed
+		// an element of the same array first
+		if a, ok := lookup(ptr[:n-1], doc).([]interface{}); ok && len(a) > 0 {
+			target = ptr[:n-1].child(indexToken(len(a) - 1))
+		}
+	}
+	if op.Op != "remove" {
+		newValue = lookup(target, doc)
+	}
+	if err := o.AfterOp(index, op, oldValue, newValue); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,72 @@
+package rfc6902
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_ApplyOptions_Hooks(t *testing.T) {
+	p, _ := ParsePatch(strings.NewReader(`[
+		{"op": "replace", "path": "/a", "value": 2},
+		{"op": "add", "path": "/l/-", "value": "z"},
+		{"op": "remove", "path": "/l/0"},
+		{"op": "move", "from": "/a", "path": "/b"},
+		{"op": "move", "from": "/n/0", "path": "/n/-"}
+	]`))
+
+	var log []string
+	o := &ApplyOptions{
+		BeforeOp: func(i int, op Operation, doc interface{}) error {
+			log = append(log, fmt.Sprintf("before %d %s %s", i, op.Op, op.Path))
+			return nil
+		},
+		AfterOp: func(i int, op Operation, oldValue, newValue interface{}) error {
+			log = append(log, fmt.Sprintf("after %d %v %v", i, oldValue, newValue))
+			return nil
+		},
+	}
+	actual, err := p.ApplyWithOptions([]byte(`{"a": 1, "l": ["x"], "n": [1, 2, 3]}`), o)
+	if err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	if !jsonEqual(actual, []byte(`{"b": 2, "l": ["z"], "n": [2, 3, 1]}`)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, `{"b": 2, "l": ["z"], "n": [2, 3, 1]}`)
+	}
+	expected := []string{
+		"before 0 replace /a", "after 0 1 2",
+		"before 1 add /l/-", "after 1 <nil> z",
+		"before 2 remove /l/0", "after 2 x <nil>",
+		"before 3 move /b", "after 3 <nil> 2",
+		"before 4 move /n/-", "after 4 <nil> 1",
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("(actual) %q != %q (expected)", log, expected)
+	}
+}
+
+func Test_ApplyOptions_Veto(t *testing.T) {
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/id"}]`))
+	veto := errors.New("id is read-only")
+	calls := 0
+	o := &ApplyOptions{
+		BeforeOp: func(i int, op Operation, doc interface{}) error {
+			if op.Path == "/id" {
+				return veto
+			}
+			return nil
+		},
+		AfterOp: func(i int, op Operation, oldValue, newValue interface{}) error {
+			calls++
+			return nil
+		},
+	}
+	if _, err := p.ApplyWithOptions([]byte(`{"id": 7}`), o); err != veto {
+		t.Errorf("(actual) %v != %v (expected)", err, veto)
+	}
+	if calls != 1 {
+		t.Errorf("AfterOp calls (actual) %d != 1 (expected)", calls)
+	}
+}
@@ -2,7 +2,6 @@ package rfc6902
 
 import (
 	"errors"
-	"fmt"
 	"strconv"
 )
 
@@ -63,8 +62,8 @@ func (p *patcher) setExistingValue(v interface{}) error {
 			na = append(pa, v)
 		} else {
 			i, err := strconv.Atoi(index)
-			if err != nil {
-				return err
+			if err != nil || i < 0 || i > len(pa) {
+				return ErrorInvalidJSONPath
 			}
 			na = append(pa[:i], append([]interface{}{v}, pa[i:]...)...)
 		}
@@ -75,10 +74,10 @@ func (p *patcher) setExistingValue(v interface{}) error {
 			return nil
 		}
 
-		parentRef.setExistingValue(na)
+		return parentRef.replace(na)
 
 	default:
-		panic("Unknown type.")
+		return ErrorInvalidJSONPath
 	}
 	return nil
 }
@@ -108,24 +107,20 @@ func (p *patcher) remove() error {
 		delete(t, p.pointer[len(p.pointer)-1].token())
 	case []interface{}:
 		i, err := strconv.Atoi(p.pointer[len(p.pointer)-1].token())
-		if err != nil {
-			return err
-		}
-		parentValue, err := parent.value()
-		if err != nil {
-			return err
-		}
-		container, ok := parentValue.([]interface{})
-		if !ok {
-			panic("Unable to convert parent to []interface{}")
+		if err != nil || i < 0 || i >= len(t) {
+			return ErrorInvalidJSONPath
 		}
 
 		newArray := make([]interface{}, 0)
-		newArray = append(newArray, container[:i]...)
-		newArray = append(newArray, container[i+1:]...)
-		parent.setExistingValue(newArray)
+		newArray = append(newArray, t[:i]...)
+		newArray = append(newArray, t[i+1:]...)
+		if parent == nil {
+			p.setParentValue(newArray)
+			return nil
+		}
+		return parent.replace(newArray)
 	default:
-		panic("Unknown type for removal")
+		return ErrorInvalidJSONPath
 	}
 	return nil
 }
@@ -138,23 +133,15 @@ func (p *patcher) replace(o interface{}) error {
 
 	switch t := ref.(type) {
 	case map[string]interface{}:
-		_ = t
-		p.setExistingValue(o)
+		return p.setExistingValue(o)
 	case []interface{}:
-		parent := p.parent()
 		i, err := strconv.Atoi(p.pointer[len(p.pointer)-1].token())
-		if err != nil {
-			return err
-		}
-		parentValue, err := parent.value()
-		if err != nil {
-			return err
+		if err != nil || i < 0 || i >= len(t) {
+			return ErrorInvalidJSONPath
 		}
-		container, ok := parentValue.([]interface{})
-		if !ok {
-			panic("Unable to convert parent to []interface{}")
-		}
-		container[i] = o
+		t[i] = o
+	default:
+		return ErrorInvalidJSONPath
 	}
 	return nil
 }
@@ -174,17 +161,14 @@ func value(fields jsonptr, ref *interface{}) (value *interface{}, err error) {
 			ref = &vv
 		case []interface{}:
 			idx, err := strconv.Atoi(string(el))
-			if err != nil {
-				panic("unable to convert to integer")
-			}
-			if idx >= len(t) {
+			if err != nil || idx < 0 || idx >= len(t) {
 				return nil, ErrorInvalidJSONPath
 			}
 			vv := t[idx]
 			value = &vv
 			ref = &vv
 		default:
-			panic(fmt.Sprintf("unknown type %T\n", t))
+			return nil, ErrorInvalidJSONPath
 		}
 	}
 	return
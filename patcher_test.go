@@ -25,7 +25,7 @@ func Test_Patcher_AddToExisting(t *testing.T) {
 		{"/a", "{}", `{"a": "b"}`},
 		{"/1", `["a", "c"]`, `["a", "b", "c"]`},
 		{"/foo/1", `{"foo": ["a", "c"]}`, `{"foo": ["a", "b", "c"]}`},
-		//{"/foo/1/1", `{"foo": ["a", ["a", "c"]]}`, `{"foo": ["a", ["a", "b", "c"]]}`},
+		{"/foo/1/1", `{"foo": ["a", ["a", "c"]]}`, `{"foo": ["a", ["a", "b", "c"]]}`},
 	}
 	for i, test := range tests {
 		p := &patcher{ptr(test.path), um(test.target)}
@@ -0,0 +1,139 @@
+package rfc6902
+
+import (
+	"fmt"
+)
+
+// Effect of a matching Rule.
+type Effect int
+
+const (
+	Allow Effect = iota
+	Deny
+)
+
+/*
+Rule matches operations by name and by the locations they touch.
+
+Pattern is a JSON Pointer matching its location and everything below it.
+Within a pattern the reference token "*" matches any single token and "**"
+any number of tokens, so "/owner/**" matches "/owner" and "/owner/name".
+
+A Deny rule also matches operations on a location above the pattern, since
+replacing or removing "/owner" touches "/owner/name" as well.  An Allow rule
+only matches at or below the pattern.
+*/
+type Rule struct {
+	Effect Effect
+	// Ops lists the operation names the rule applies to, all when empty.
+	Ops     []string
+	Pattern string
+}
+
+// Policy restricts the locations a patch may touch.  Every path and from of
+// every operation is checked against the rules in order; the first matching
+// rule decides, Default applies when none does.
+type Policy struct {
+	Rules   []Rule
+	Default Effect
+}
+
+// PolicyViolation is returned by Policy.Check.
+type PolicyViolation struct {
+	Index     int
+	Operation Operation
+	// Pointer is the offending path or from member.
+	Pointer string
+	// Rule is the denying rule, nil when denied by default.
+	Rule *Rule
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("rfc6902: %s at %d may not touch %q", v.Operation.Op, v.Index, v.Pointer)
+}
+
+// Check returns a *PolicyViolation for the first operation of p the policy
+// does not allow.
+func (pol *Policy) Check(p *Patcher) error {
+	for i, op := range p.ops {
+		pointers := []string{op.Path}
+		if op.Op == "move" || op.Op == "copy" {
+			pointers = append(pointers, op.From)
+		}
+		for _, s := range pointers {
+			ptr, err := newJSONPointer(s)
+			if err != nil {
+				return err
+			}
+			rule, effect := pol.decide(op.Op, ptr)
+			if effect == Deny {
+				return &PolicyViolation{Index: i, Operation: op, Pointer: s, Rule: rule}
+			}
+		}
+	}
+	return nil
+}
+
+func (pol *Policy) decide(op string, ptr jsonptr) (*Rule, Effect) {
+	for i := range pol.Rules {
+		r := &pol.Rules[i]
+		if r.applies(op) && r.matches(ptr) {
+			return r, r.Effect
+		}
+	}
+	return nil, pol.Default
+}
+
+func (r *Rule) applies(op string) bool {
+	if len(r.Ops) == 0 {
+		return true
+	}
+	for _, o := range r.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(ptr jsonptr) bool {
+	pat, err := newJSONPointer(r.Pattern)
+	if err != nil {
+		return false
+	}
+	tokens := make([]string, 0, len(pat)+1)
+	for _, t := range pat {
+		tokens = append(tokens, t.token())
+	}
+	if len(tokens) == 0 || tokens[len(tokens)-1] != "**" {
+		tokens = append(tokens, "**")
+	}
+	return glob(tokens, ptr, r.Effect == Deny)
+}
+
+// glob matches ptr against pattern tokens.  With ancestors set a pointer
+// that ends before the pattern does matches as well.
+func glob(pat []string, ptr jsonptr, ancestors bool) bool {
+	if len(ptr) == 0 {
+		if ancestors {
+			return true
+		}
+		for _, t := range pat {
+			if t != "**" {
+				return false
+			}
+		}
+		return true
+	}
+	if len(pat) == 0 {
+		return false
+	}
+	switch pat[0] {
+	case "**":
+		return glob(pat[1:], ptr, ancestors) || glob(pat, ptr[1:], ancestors)
+	case "*":
+		return glob(pat[1:], ptr[1:], ancestors)
+	default:
+		return pat[0] == ptr[0].token() && glob(pat[1:], ptr[1:], ancestors)
+	}
+}
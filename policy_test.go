@@ -0,0 +1,71 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Policy_Check(t *testing.T) {
+	readOnly := &Policy{Rules: []Rule{
+		{Effect: Deny, Pattern: "/id"},
+		{Effect: Deny, Pattern: "/owner/**"},
+		{Effect: Deny, Pattern: "/audit"},
+	}}
+	tagsOnly := &Policy{Rules: []Rule{
+		{Effect: Allow, Ops: []string{"test"}, Pattern: "/**"},
+		{Effect: Allow, Ops: []string{"remove"}, Pattern: "/tags"},
+		{Effect: Allow, Ops: []string{"replace"}, Pattern: "/items/*/name"},
+	}, Default: Deny}
+
+	tests := []struct {
+		policy  *Policy
+		patch   string
+		index   int
+		pointer string
+	}{
+		{readOnly, `[{"op": "replace", "path": "/name", "value": 1}]`, -1, ""},
+		{readOnly, `[{"op": "replace", "path": "/name", "value": 1}, {"op": "replace", "path": "/id", "value": 1}]`, 1, "/id"},
+		{readOnly, `[{"op": "add", "path": "/idx", "value": 1}]`, -1, ""},
+		{readOnly, `[{"op": "remove", "path": "/owner/name"}]`, 0, "/owner/name"},
+		{readOnly, `[{"op": "remove", "path": "/owner"}]`, 0, "/owner"},
+		{readOnly, `[{"op": "copy", "from": "/id", "path": "/ref"}]`, 0, "/id"},
+		{readOnly, `[{"op": "move", "from": "/audit/0", "path": "/log"}]`, 0, "/audit/0"},
+		{readOnly, `[{"op": "replace", "path": "#/i%64", "value": 1}]`, 0, "#/i%64"},
+		{tagsOnly, `[{"op": "test", "path": "/id", "value": 1}, {"op": "remove", "path": "/tags/3"}]`, -1, ""},
+		{tagsOnly, `[{"op": "remove", "path": "/name"}]`, 0, "/name"},
+		{tagsOnly, `[{"op": "add", "path": "/tags/-", "value": "x"}]`, 0, "/tags/-"},
+		{tagsOnly, `[{"op": "replace", "path": "/items/2/name", "value": "x"}]`, -1, ""},
+		{tagsOnly, `[{"op": "replace", "path": "/items/2", "value": "x"}]`, 0, "/items/2"},
+	}
+
+	for i, test := range tests {
+		p, err := ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Failed parsing: %q. %s", i, test.patch, err)
+		}
+		err = test.policy.Check(p)
+		if test.index < 0 {
+			if err != nil {
+				t.Errorf("%d: unexpected violation %s", i, err)
+			}
+			continue
+		}
+		v, ok := err.(*PolicyViolation)
+		if !ok {
+			t.Errorf("%d: (actual) %v != violation (expected)", i, err)
+			continue
+		}
+		if v.Index != test.index || v.Pointer != test.pointer {
+			t.Errorf("%d: (actual) %d %s != %d %s (expected)", i, v.Index, v.Pointer, test.index, test.pointer)
+		}
+	}
+}
+
+func Test_Policy_Apply(t *testing.T) {
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/id"}]`))
+	o := &ApplyOptions{Policy: &Policy{Rules: []Rule{{Effect: Deny, Pattern: "/id"}}}}
+	_, err := p.ApplyWithOptions([]byte(`{"id": 1}`), o)
+	if v, ok := err.(*PolicyViolation); !ok || v.Index != 1 {
+		t.Errorf("(actual) %v != violation at 1 (expected)", err)
+	}
+}
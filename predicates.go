@@ -0,0 +1,239 @@
+package rfc6902
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/*
+predicates holds the predicate operations enabled by Parser.Predicates, after
+draft-snell-json-test.  A predicate that does not hold fails the patch with
+ErrorTestFailed, like test:
+
+	defined     path exists
+	undefined   path does not exist
+	type        the value has the JSON type named by value: null, boolean,
+	            number, string, array or object, or is undefined
+	contains    the string contains the string value, or the array an element
+	            equal to value
+	starts      the string starts with the string value
+	ends        the string ends with the string value
+	matches     the string matches the regular expression value
+	less        the number is less than value
+	more        the number is more than value
+	in          the value equals an element of the array value
+	and         every predicate of apply holds
+	or          some predicate of apply holds
+	not         no predicate of apply holds
+
+The paths of the predicates in apply are relative to the path of the
+combinator; test may be used among them.  With ignore_case set strings are
+compared case-insensitively, by test as well.
+*/
+var predicates = map[string]predicateOp{
+	"defined":   {},
+	"undefined": {},
+	"type":      {"value"},
+	"contains":  {"value"},
+	"starts":    {"value"},
+	"ends":      {"value"},
+	"matches":   {"value"},
+	"less":      {"value"},
+	"more":      {"value"},
+	"in":        {"value"},
+	"and":       {},
+	"or":        {},
+	"not":       {},
+}
+
+// predicateOp lists the members a predicate operation requires.
+type predicateOp []string
+
+func (h predicateOp) Members() []string {
+	return h
+}
+
+func (h predicateOp) Apply(doc *Document, path Pointer, op Operation) error {
+	if !satisfied(op, path.ptr(), doc.Root()) {
+		return ErrorTestFailed
+	}
+	return nil
+}
+
+func (h predicateOp) check(op Operation, found members) error {
+	switch op.Op {
+	case "type":
+		switch op.Value {
+		case "null", "boolean", "number", "string", "array", "object", "undefined":
+		default:
+			return fmt.Errorf("unknown type %v", op.Value)
+		}
+	case "starts", "ends", "matches":
+		s, ok := op.Value.(string)
+		if !ok {
+			return fmt.Errorf("%s needs a string value", op.Op)
+		}
+		if op.Op == "matches" {
+			if _, err := pattern(s, op.IgnoreCase); err != nil {
+				return err
+			}
+		}
+	case "less", "more":
+		if _, ok := op.Value.(float64); !ok {
+			return fmt.Errorf("%s needs a number value", op.Op)
+		}
+	case "in":
+		if _, ok := op.Value.([]interface{}); !ok {
+			return errors.New("in needs an array value")
+		}
+	case "and", "or", "not":
+		if len(op.Predicates) == 0 {
+			return fmt.Errorf("%s needs predicates to apply", op.Op)
+		}
+		for i, n := range op.Predicates {
+			var nested members
+			if i < len(found.Apply) {
+				nested = found.Apply[i]
+			}
+			if _, err := newJSONPointer(n.Path); err != nil {
+				return err
+			}
+			if n.Op == "test" {
+				continue
+			}
+			h, ok := predicates[n.Op]
+			if !ok {
+				return fmt.Errorf("%q is not a predicate", n.Op)
+			}
+			if err := checkMembers(h, n, nested, 0); err != nil {
+				return err
+			}
+			if err := h.check(n, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// satisfied reports whether the predicate op holds for the value at at.
+func satisfied(op Operation, at jsonptr, doc interface{}) bool {
+	v, exists := doc, true
+	if len(at) > 0 {
+		p := patcher{at, doc}
+		if exists = p.exists(); exists {
+			v, _ = p.value()
+		}
+	}
+
+	switch op.Op {
+	case "defined":
+		return exists
+	case "undefined":
+		return !exists
+	case "type":
+		if !exists {
+			return op.Value == "undefined"
+		}
+		name := typeName(v)
+		if name == "integer" {
+			name = "number"
+		}
+		return name == op.Value
+	case "and", "or", "not":
+		n := 0
+		for _, c := range op.Predicates {
+			ptr, err := newJSONPointer(c.Path)
+			if err != nil {
+				return false
+			}
+			if satisfied(c, append(append(jsonptr{}, at...), ptr...), doc) {
+				n++
+			}
+		}
+		switch op.Op {
+		case "and":
+			return n == len(op.Predicates)
+		case "or":
+			return n > 0
+		}
+		return n == 0
+	}
+	if !exists {
+		return false
+	}
+
+	switch op.Op {
+	case "test":
+		return equalValue(op.Value, v, op.IgnoreCase)
+	case "in":
+		for _, e := range op.Value.([]interface{}) {
+			if equalValue(e, v, op.IgnoreCase) {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		if a, ok := v.([]interface{}); ok {
+			for _, e := range a {
+				if equalValue(op.Value, e, op.IgnoreCase) {
+					return true
+				}
+			}
+			return false
+		}
+	case "less", "more":
+		n, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		if op.Op == "less" {
+			return n < op.Value.(float64)
+		}
+		return n > op.Value.(float64)
+	}
+
+	s, ok := v.(string)
+	t, tok := op.Value.(string)
+	if !ok || !tok {
+		return false
+	}
+	if op.Op == "matches" {
+		re, err := pattern(t, op.IgnoreCase)
+		return err == nil && re.MatchString(s)
+	}
+	if op.IgnoreCase {
+		s, t = strings.ToLower(s), strings.ToLower(t)
+	}
+	switch op.Op {
+	case "contains":
+		return strings.Contains(s, t)
+	case "starts":
+		return strings.HasPrefix(s, t)
+	case "ends":
+		return strings.HasSuffix(s, t)
+	}
+	return false
+}
+
+func pattern(expr string, ignoreCase bool) (*regexp.Regexp, error) {
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// equalValue is sameValue, comparing strings case-insensitively when
+// ignoreCase is set.
+func equalValue(a, b interface{}, ignoreCase bool) bool {
+	if ignoreCase {
+		s, ok := a.(string)
+		t, tok := b.(string)
+		if ok && tok {
+			return strings.EqualFold(s, t)
+		}
+	}
+	return sameValue(a, b)
+}
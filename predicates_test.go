@@ -0,0 +1,118 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Predicates(t *testing.T) {
+	doc := []byte(`{"name": "Alice Smith", "age": 30, "tags": ["a", "B"], "none": null, "o": {"v": 2, "s": "x"}}`)
+	tests := []struct {
+		predicate string
+		holds     bool
+	}{
+		{`{"op": "defined", "path": "/name"}`, true},
+		{`{"op": "defined", "path": "/missing"}`, false},
+		{`{"op": "defined", "path": "/none"}`, true},
+		{`{"op": "undefined", "path": "/missing"}`, true},
+		{`{"op": "undefined", "path": "/age"}`, false},
+		{`{"op": "type", "path": "/age", "value": "number"}`, true},
+		{`{"op": "type", "path": "/none", "value": "null"}`, true},
+		{`{"op": "type", "path": "/o", "value": "array"}`, false},
+		{`{"op": "type", "path": "/missing", "value": "undefined"}`, true},
+		{`{"op": "contains", "path": "/name", "value": "ce S"}`, true},
+		{`{"op": "contains", "path": "/name", "value": "SMITH"}`, false},
+		{`{"op": "contains", "path": "/name", "value": "SMITH", "ignore_case": true}`, true},
+		{`{"op": "contains", "path": "/tags", "value": "b", "ignore_case": true}`, true},
+		{`{"op": "contains", "path": "/tags", "value": "c"}`, false},
+		{`{"op": "starts", "path": "/name", "value": "Alice"}`, true},
+		{`{"op": "ends", "path": "/name", "value": "Alice"}`, false},
+		{`{"op": "ends", "path": "/age", "value": "0"}`, false},
+		{`{"op": "matches", "path": "/name", "value": "^[A-Z][a-z]+ [A-Z]"}`, true},
+		{`{"op": "matches", "path": "/name", "value": "^alice", "ignore_case": true}`, true},
+		{`{"op": "less", "path": "/age", "value": 31}`, true},
+		{`{"op": "less", "path": "/age", "value": 30}`, false},
+		{`{"op": "more", "path": "/age", "value": 18}`, true},
+		{`{"op": "more", "path": "/name", "value": 18}`, false},
+		{`{"op": "in", "path": "/age", "value": [10, 30]}`, true},
+		{`{"op": "in", "path": "/missing", "value": [null]}`, false},
+		{`{"op": "test", "path": "/name", "value": "alice smith", "ignore_case": true}`, true},
+		{`{"op": "and", "path": "/o", "apply": [{"op": "test", "path": "/v", "value": 2}, {"op": "defined", "path": "/s"}]}`, true},
+		{`{"op": "and", "path": "/o", "apply": [{"op": "test", "path": "/v", "value": 2}, {"op": "defined", "path": "/t"}]}`, false},
+		{`{"op": "or", "path": "/o", "apply": [{"op": "less", "path": "/v", "value": 0}, {"op": "type", "path": "/s", "value": "string"}]}`, true},
+		{`{"op": "or", "path": "/o", "apply": [{"op": "less", "path": "/v", "value": 0}]}`, false},
+		{`{"op": "not", "path": "/o", "apply": [{"op": "defined", "path": "/t"}]}`, true},
+		{`{"op": "contains", "path": "/tags", "value": null}`, false},
+		{`{"op": "not", "path": "/tags", "apply": [{"op": "contains", "path": "", "value": null}]}`, true},
+		{`{"op": "not", "path": "/o", "apply": [{"op": "and", "path": "", "apply": [{"op": "defined", "path": "/v"}]}]}`, false},
+	}
+
+	ps := &Parser{Predicates: true}
+	for i, test := range tests {
+		p, err := ps.Parse(strings.NewReader("[" + test.predicate + "]"))
+		if err != nil {
+			t.Fatalf("%d: Parse failed: %s", i, err)
+		}
+		_, err = p.Apply(doc)
+		if test.holds && err != nil {
+			t.Errorf("%d: %s failed: %s", i, test.predicate, err)
+		}
+		if !test.holds && err != ErrorTestFailed {
+			t.Errorf("%d: %s (actual) %v != %v (expected)", i, test.predicate, err, ErrorTestFailed)
+		}
+	}
+}
+
+func Test_Predicates_Parse(t *testing.T) {
+	ps := &Parser{Predicates: true}
+	for i, predicate := range []string{
+		`{"op": "type", "path": "/a", "value": "date"}`,
+		`{"op": "type", "path": "/a"}`,
+		`{"op": "starts", "path": "/a", "value": 1}`,
+		`{"op": "matches", "path": "/a", "value": "("}`,
+		`{"op": "less", "path": "/a", "value": "1"}`,
+		`{"op": "in", "path": "/a", "value": 1}`,
+		`{"op": "and", "path": "/a"}`,
+		`{"op": "or", "path": "/a", "apply": [{"op": "remove", "path": "/b"}]}`,
+		`{"op": "not", "path": "/a", "apply": [{"op": "more", "path": "/b", "value": "x"}]}`,
+	} {
+		if _, err := ps.Parse(strings.NewReader("[" + predicate + "]")); err == nil {
+			t.Errorf("%d: expected an error for %s", i, predicate)
+		}
+	}
+
+	if _, err := ParsePatch(strings.NewReader(`[{"op": "defined", "path": "/a"}]`)); err == nil {
+		t.Errorf("expected strict parsing to reject defined")
+	}
+
+	// strict parsing ignores the members of predicate operations
+	p, err := ParsePatch(strings.NewReader(`[{"op": "test", "path": "/a", "value": "X", "ignore_case": true}]`))
+	if err != nil {
+		t.Fatalf("ParsePatch failed: %s", err)
+	}
+	if _, err := p.Apply([]byte(`{"a": "x"}`)); err != ErrorTestFailed {
+		t.Errorf("(actual) %v != %v (expected)", err, ErrorTestFailed)
+	}
+}
+
+func Test_Predicates_Guard(t *testing.T) {
+	p, err := (&Parser{Predicates: true}).Parse(strings.NewReader(`[
+		{"op": "less", "path": "/stock", "value": 10},
+		{"op": "replace", "path": "/stock", "value": 100},
+		{"op": "type", "path": "/restocked", "value": "undefined"},
+		{"op": "add", "path": "/restocked", "value": true}
+	]`))
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	actual, err := p.Apply([]byte(`{"stock": 3}`))
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if !jsonEqual(actual, []byte(`{"stock": 100, "restocked": true}`)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, `{"stock": 100, "restocked": true}`)
+	}
+	if _, err := p.Apply([]byte(`{"stock": 30}`)); err != ErrorTestFailed {
+		t.Errorf("(actual) %v != %v (expected)", err, ErrorTestFailed)
+	}
+}
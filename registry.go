@@ -0,0 +1,150 @@
+package rfc6902
+
+import (
+	"fmt"
+)
+
+/*
+An OperationHandler implements an operation registered with
+Parser.RegisterOperation.
+
+Members lists the members besides op and path every operation of the kind must
+have, "value" or "from"; Parse rejects operations missing one.  Apply is called
+with the path of the operation, a trailing "-" replaced by the index past the
+end of the array, and changes doc in place.  Returning an error fails the
+patch.
+*/
+type OperationHandler interface {
+	Members() []string
+	Apply(doc *Document, path Pointer, op Operation) error
+}
+
+// Pointer is a JSON Pointer split into its unescaped reference tokens.
+type Pointer []string
+
+// String returns the escaped pointer.
+func (p Pointer) String() (s string) {
+	for _, t := range p {
+		s += "/" + encode(t)
+	}
+	return
+}
+
+func (p Pointer) ptr() jsonptr {
+	j := make(jsonptr, len(p))
+	for i, t := range p {
+		j[i] = reftoken(encode(t))
+	}
+	return j
+}
+
+// Document is the document being patched, as seen by an OperationHandler.
+type Document struct {
+	root interface{}
+}
+
+// Root returns the document.  Values returned by Root and Get must be changed
+// through the other methods only.
+func (d *Document) Root() interface{} {
+	return d.root
+}
+
+// Get returns the value at path, or ErrorInvalidJSONPath if there is none.
+func (d *Document) Get(path Pointer) (interface{}, error) {
+	if len(path) == 0 {
+		return d.root, nil
+	}
+	p := patcher{path.ptr(), d.root}
+	if !p.exists() {
+		return nil, ErrorInvalidJSONPath
+	}
+	return p.value()
+}
+
+// Add adds v at path like the add operation.
+func (d *Document) Add(path Pointer, v interface{}) error {
+	return d.do(Operation{Op: "add", Path: path.String(), Value: v})
+}
+
+// Replace replaces the value at path like the replace operation.
+func (d *Document) Replace(path Pointer, v interface{}) error {
+	return d.do(Operation{Op: "replace", Path: path.String(), Value: v})
+}
+
+// Remove removes the value at path like the remove operation.
+func (d *Document) Remove(path Pointer) error {
+	return d.do(Operation{Op: "remove", Path: path.String()})
+}
+
+func (d *Document) do(op Operation) error {
+	v, err := op.apply(d.root)
+	if err != nil {
+		return err
+	}
+	d.root = v
+	return nil
+}
+
+// RegisterOperation makes ps accept operations named name and apply them with
+// h.  A registered operation takes precedence over an extended one of the
+// same name; the standard operations cannot be replaced.
+func (ps *Parser) RegisterOperation(name string, h OperationHandler) error {
+	switch name {
+	case "", "add", "remove", "replace", "move", "copy", "test":
+		return fmt.Errorf("rfc6902: cannot register op %q", name)
+	}
+	for _, m := range h.Members() {
+		if m != "value" && m != "from" {
+			return fmt.Errorf("rfc6902: unsupported member %q for op %q", m, name)
+		}
+	}
+	if ps.handlers == nil {
+		ps.handlers = make(map[string]OperationHandler)
+	}
+	ps.handlers[name] = h
+	return nil
+}
+
+// handler returns the handler for a non-standard op, or nil.
+func (ps *Parser) handler(op string) OperationHandler {
+	if h, ok := ps.handlers[op]; ok {
+		return h
+	}
+	if h, ok := extended[op]; ok && ps.Extended {
+		return h
+	}
+	if h, ok := predicates[op]; ok && ps.Predicates {
+		return h
+	}
+	return nil
+}
+
+// checker is implemented by built-in handlers that validate operations
+// beyond their members when parsed.
+type checker interface {
+	check(op Operation, found members) error
+}
+
+// checkMembers returns an error if op, read with the members found, lacks a
+// member h requires.
+func checkMembers(h OperationHandler, op Operation, found members, pos int) error {
+	for _, m := range h.Members() {
+		if !found.has(m) {
+			return fmt.Errorf("rfc6902: missing %s for %s op at %d", m, op.Op, pos)
+		}
+	}
+	return nil
+}
+
+func (o *Operation) applyHandler(ptr jsonptr, v interface{}) (interface{}, error) {
+	ptr = resolve(ptr, v)
+	path := make(Pointer, len(ptr))
+	for i, t := range ptr {
+		path[i] = t.token()
+	}
+	doc := &Document{v}
+	if err := o.handler.Apply(doc, path, *o); err != nil {
+		return nil, err
+	}
+	return doc.root, nil
+}
@@ -0,0 +1,134 @@
+package rfc6902
+
+import (
+	"strings"
+	"testing"
+)
+
+// redact replaces every string at or below path with "***".
+type redact struct{}
+
+func (redact) Members() []string { return nil }
+
+func (redact) Apply(doc *Document, path Pointer, op Operation) error {
+	v, err := doc.Get(path)
+	if err != nil {
+		return err
+	}
+	return doc.Replace(path, redacted(clone(v)))
+}
+
+func redacted(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return "***"
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = redacted(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = redacted(e)
+		}
+	}
+	return v
+}
+
+// record adds the pointer it was called with at from.
+type record struct{}
+
+func (record) Members() []string { return []string{"from"} }
+
+func (record) Apply(doc *Document, path Pointer, op Operation) error {
+	return doc.Add(Pointer{strings.TrimPrefix(op.From, "/")}, path.String())
+}
+
+// double overrides the extended inc.
+type double struct{}
+
+func (double) Members() []string { return []string{"value"} }
+
+func (double) Apply(doc *Document, path Pointer, op Operation) error {
+	v, err := doc.Get(path)
+	if err != nil {
+		return err
+	}
+	return doc.Replace(path, 2*v.(float64))
+}
+
+func Test_RegisterOperation(t *testing.T) {
+	ps := &Parser{Extended: true}
+	for name, h := range map[string]OperationHandler{"redact": redact{}, "record": record{}, "inc": double{}} {
+		if err := ps.RegisterOperation(name, h); err != nil {
+			t.Fatalf("RegisterOperation(%q) failed: %s", name, err)
+		}
+	}
+
+	doc := `{"user": {"name": "a", "cards": ["1234", 5]}, "list": [1], "n": 3, "s": "x"}`
+	tests := []struct {
+		patch, expected string
+	}{
+		{`[{"op": "redact", "path": "/user"}]`,
+			`{"user": {"name": "***", "cards": ["***", 5]}, "list": [1], "n": 3, "s": "x"}`},
+		{`[{"op": "record", "path": "/list/-", "from": "/at"}]`,
+			`{"user": {"name": "a", "cards": ["1234", 5]}, "list": [1], "n": 3, "s": "x", "at": "/list/1"}`},
+		// an empty from is the root, not a missing member
+		{`[{"op": "record", "path": "/n", "from": ""}]`,
+			`{"user": {"name": "a", "cards": ["1234", 5]}, "list": [1], "n": 3, "s": "x", "": "/n"}`},
+		{`[{"op": "inc", "path": "/n", "value": 1}, {"op": "str-append", "path": "/s", "value": "y"}]`,
+			`{"user": {"name": "a", "cards": ["1234", 5]}, "list": [1], "n": 6, "s": "xy"}`},
+	}
+	for i, test := range tests {
+		p, err := ps.Parse(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: Parse failed: %s", i, err)
+		}
+		actual, err := p.Apply([]byte(doc))
+		if err != nil {
+			t.Errorf("%d: Apply failed: %s", i, err)
+			continue
+		}
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+
+	if _, err := ps.Parse(strings.NewReader(`[{"op": "record", "path": "/a"}]`)); err == nil {
+		t.Errorf("expected an error for a missing from")
+	}
+	if _, err := ps.Parse(strings.NewReader(`[{"op": "inc", "path": "/n"}]`)); err == nil {
+		t.Errorf("expected an error for a missing value")
+	}
+
+	// handlers belong to the parser they were registered with
+	p, err := ParsePatch(strings.NewReader(`[{"op": "redact", "path": "/user"}]`))
+	if err != nil {
+		t.Fatalf("ParsePatch failed: %s", err)
+	}
+	if _, err := p.Apply([]byte(doc)); err == nil {
+		t.Errorf("expected an unknown operation error")
+	}
+}
+
+func Test_RegisterOperation_Errors(t *testing.T) {
+	ps := new(Parser)
+	for _, name := range []string{"", "add", "remove", "replace", "move", "copy", "test"} {
+		if err := ps.RegisterOperation(name, redact{}); err == nil {
+			t.Errorf("expected an error registering %q", name)
+		}
+	}
+	if err := ps.RegisterOperation("bad", badMembers{}); err == nil {
+		t.Errorf("expected an error for an unsupported member")
+	}
+}
+
+type badMembers struct{ redact }
+
+func (badMembers) Members() []string { return []string{"key"} }
+
+func Test_Pointer(t *testing.T) {
+	p := Pointer{"a/b", "m~n", ""}
+	if s := p.String(); s != "/a~1b/m~0n/" {
+		t.Errorf("(actual) %q != %q (expected)", s, "/a~1b/m~0n/")
+	}
+}
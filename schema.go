@@ -0,0 +1,413 @@
+package rfc6902
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Schema is a compiled JSON Schema (draft 2020-12).  The supported keywords are
+type, enum, properties, required, additionalProperties, items, minimum,
+maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern,
+minItems and maxItems, as well as the boolean schemas true and false.  Other
+keywords are ignored.
+*/
+type Schema struct {
+	reject               bool
+	types                []string
+	enum                 []interface{}
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	items                *Schema
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	minLength, maxLength *int
+	minItems, maxItems   *int
+	pattern              *regexp.Regexp
+}
+
+// ParseSchema reads and compiles a JSON Schema.
+func ParseSchema(r io.Reader) (*Schema, error) {
+	if r == nil {
+		return nil, errors.New("reader is nil")
+	}
+	b := new(bytes.Buffer)
+	b.ReadFrom(r)
+	s := new(Schema)
+	if err := json.Unmarshal(b.Bytes(), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// UnmarshalJSON compiles a JSON Schema.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	var accept bool
+	if err := json.Unmarshal(b, &accept); err == nil {
+		*s = Schema{reject: !accept}
+		return nil
+	}
+
+	var raw struct {
+		Type                 interface{}        `json:"type"`
+		Enum                 []interface{}      `json:"enum"`
+		Properties           map[string]*Schema `json:"properties"`
+		Required             []string           `json:"required"`
+		AdditionalProperties *Schema            `json:"additionalProperties"`
+		Items                *Schema            `json:"items"`
+		Minimum              *float64           `json:"minimum"`
+		Maximum              *float64           `json:"maximum"`
+		ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+		ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+		MinLength            *int               `json:"minLength"`
+		MaxLength            *int               `json:"maxLength"`
+		MinItems             *int               `json:"minItems"`
+		MaxItems             *int               `json:"maxItems"`
+		Pattern              *string            `json:"pattern"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*s = Schema{
+		enum:                 raw.Enum,
+		properties:           raw.Properties,
+		required:             raw.Required,
+		additionalProperties: raw.AdditionalProperties,
+		items:                raw.Items,
+		minimum:              raw.Minimum,
+		maximum:              raw.Maximum,
+		exclusiveMinimum:     raw.ExclusiveMinimum,
+		exclusiveMaximum:     raw.ExclusiveMaximum,
+		minLength:            raw.MinLength,
+		maxLength:            raw.MaxLength,
+		minItems:             raw.MinItems,
+		maxItems:             raw.MaxItems,
+	}
+	switch t := raw.Type.(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, e := range t {
+			name, ok := e.(string)
+			if !ok {
+				return fmt.Errorf("rfc6902: schema type must be a string: %v", e)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return fmt.Errorf("rfc6902: schema type must be a string or an array: %v", t)
+	}
+	if raw.Pattern != nil {
+		re, err := regexp.Compile(*raw.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	return nil
+}
+
+// SchemaViolation is a location in a document failing a schema keyword.
+type SchemaViolation struct {
+	// Pointer is the location of the failing value.
+	Pointer string
+	Keyword string
+	Message string
+	// Index is the index of the operation that last touched the location,
+	// -1 when the value was not changed by the patch.
+	Index int
+
+	// at is the location an operation must touch to cause the violation
+	at jsonptr
+}
+
+// SchemaError lists every violation found in a document.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%q: %s", v.Pointer, v.Message)
+		if v.Index >= 0 {
+			msgs[i] += fmt.Sprintf(" (operation %d)", v.Index)
+		}
+	}
+	return "rfc6902: schema violation " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the JSON document b against the schema.  A *SchemaError is
+// returned when it does not conform.
+func (s *Schema) Validate(b []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	return s.check(doc, nil)
+}
+
+// check validates doc, attributing violations to the operations of p.
+func (s *Schema) check(doc interface{}, p *Patcher) error {
+	var vs []SchemaViolation
+	s.validate(jsonptr{}, doc, &vs)
+	if len(vs) == 0 {
+		return nil
+	}
+	for i := range vs {
+		vs[i].Index = -1
+		if p == nil {
+			continue
+		}
+		for j := len(p.ops) - 1; j >= 0; j-- {
+			if p.ops[j].affects(vs[i].at) {
+				vs[i].Index = j
+				break
+			}
+		}
+	}
+	return &SchemaError{Violations: vs}
+}
+
+// affects reports whether the operation changes ptr, one of its ancestors or
+// anything below it.
+func (o *Operation) affects(ptr jsonptr) bool {
+	for _, loc := range o.locations() {
+		n := commonPrefix(loc, ptr)
+		if n == len(loc) || n == len(ptr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) validate(ptr jsonptr, v interface{}, vs *[]SchemaViolation) {
+	fail := func(at jsonptr, keyword, format string, args ...interface{}) {
+		*vs = append(*vs, SchemaViolation{Pointer: ptr.path(), Keyword: keyword, Message: fmt.Sprintf(format, args...), at: at})
+	}
+
+	if s.reject {
+		fail(ptr, "false", "no value allowed")
+		return
+	}
+	if len(s.types) > 0 && !s.hasType(v) {
+		fail(ptr, "type", "expected %s, found %s", strings.Join(s.types, " or "), typeName(v))
+		return
+	}
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail(ptr, "enum", "value is not one of the allowed values")
+		}
+	}
+
+	switch t := v.(type) {
+	case float64:
+		if s.minimum != nil && t < *s.minimum {
+			fail(ptr, "minimum", "%v is less than %v", t, *s.minimum)
+		}
+		if s.maximum != nil && t > *s.maximum {
+			fail(ptr, "maximum", "%v is greater than %v", t, *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && t <= *s.exclusiveMinimum {
+			fail(ptr, "exclusiveMinimum", "%v is not greater than %v", t, *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && t >= *s.exclusiveMaximum {
+			fail(ptr, "exclusiveMaximum", "%v is not less than %v", t, *s.exclusiveMaximum)
+		}
+	case string:
+		n := utf8.RuneCountInString(t)
+		if s.minLength != nil && n < *s.minLength {
+			fail(ptr, "minLength", "length %d is less than %d", n, *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail(ptr, "maxLength", "length %d is greater than %d", n, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(t) {
+			fail(ptr, "pattern", "does not match %q", s.pattern)
+		}
+	case []interface{}:
+		if s.minItems != nil && len(t) < *s.minItems {
+			fail(ptr, "minItems", "%d items are less than %d", len(t), *s.minItems)
+		}
+		if s.maxItems != nil && len(t) > *s.maxItems {
+			fail(ptr, "maxItems", "%d items are more than %d", len(t), *s.maxItems)
+		}
+		if s.items != nil {
+			for i, e := range t {
+				s.items.validate(ptr.child(indexToken(i)), e, vs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := t[name]; !ok {
+				fail(ptr.child(reftoken(encode(name))), "required", "missing property %q", name)
+			}
+		}
+		for _, name := range sortedKeys(t) {
+			child := ptr.child(reftoken(encode(name)))
+			if ps, ok := s.properties[name]; ok {
+				ps.validate(child, t[name], vs)
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.validate(child, t[name], vs)
+			}
+		}
+	}
+}
+
+func (s *Schema) hasType(v interface{}) bool {
+	name := typeName(v)
+	for _, t := range s.types {
+		if t == name || (t == "number" && name == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON Schema type of a decoded JSON value.
+func typeName(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if t == math.Trunc(t) && !math.IsInf(t, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// checkPatch statically checks the operations of p against the schema of the
+// document they will be applied to.
+func (s *Schema) checkPatch(p *Patcher) error {
+	var vs []SchemaViolation
+	for i, op := range p.ops {
+		n := len(vs)
+		s.checkOp(op, &vs)
+		for j := n; j < len(vs); j++ {
+			vs[j].Index = i
+		}
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	return &SchemaError{Violations: vs}
+}
+
+func (s *Schema) checkOp(op Operation, vs *[]SchemaViolation) {
+	ptr, err := newJSONPointer(op.Path)
+	if err != nil || len(ptr) == 0 {
+		return
+	}
+	parent, target := s.locate(ptr)
+	name := ptr[len(ptr)-1]
+
+	switch op.Op {
+	case "add", "replace":
+		if op.Op == "add" && parent != nil && name == "-" && len(parent.types) > 0 && !parent.allows("array") {
+			*vs = append(*vs, SchemaViolation{Pointer: op.Path, Keyword: "type", Message: "cannot append to " + strings.Join(parent.types, " or "), at: ptr})
+			return
+		}
+		if target != nil {
+			target.validate(ptr, op.Value, vs)
+		}
+	case "remove":
+		parent.checkRequired(ptr, vs)
+	case "move", "copy":
+		if from, err := newJSONPointer(op.From); op.Op == "move" && err == nil && len(from) > 0 {
+			fromParent, _ := s.locate(from)
+			fromParent.checkRequired(from, vs)
+		}
+		if target != nil && target.reject {
+			target.validate(ptr, nil, vs)
+		}
+	}
+}
+
+// checkRequired adds a violation when the last token of ptr names a property
+// the schema s requires.
+func (s *Schema) checkRequired(ptr jsonptr, vs *[]SchemaViolation) {
+	if s == nil {
+		return
+	}
+	name := ptr[len(ptr)-1].token()
+	for _, r := range s.required {
+		if r == name {
+			*vs = append(*vs, SchemaViolation{Pointer: ptr.path(), Keyword: "required", Message: fmt.Sprintf("cannot remove required property %q", name), at: ptr})
+			return
+		}
+	}
+}
+
+// locate returns the schemas governing the value at ptr and its parent.  A
+// nil schema accepts anything.
+func (s *Schema) locate(ptr jsonptr) (parent, target *Schema) {
+	target = s
+	for _, tok := range ptr {
+		parent = target
+		if target == nil {
+			return nil, nil
+		}
+		target = target.member(tok)
+	}
+	return
+}
+
+// member returns the schema of a property or array element.
+func (s *Schema) member(tok reftoken) *Schema {
+	if s.reject {
+		return s
+	}
+	if ps, ok := s.properties[tok.token()]; ok && s.allows("object") {
+		return ps
+	}
+	if s.items != nil && isIndex(tok) && s.allows("array") {
+		return s.items
+	}
+	if s.allows("object") {
+		return s.additionalProperties
+	}
+	return nil
+}
+
+// allows reports whether values of the given type are allowed, which is the
+// case when the schema does not restrict the type.
+func (s *Schema) allows(name string) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+	for _, t := range s.types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package rfc6902
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var schemaDoc = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+		"state": {"enum": ["on", "off"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"score": {"type": ["number", "null"], "exclusiveMaximum": 10}
+	},
+	"additionalProperties": false
+}`
+
+func Test_Schema_Validate(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(schemaDoc))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %s", err)
+	}
+
+	tests := []struct {
+		doc      string
+		expected []string
+	}{
+		{`{"id": 1, "name": "abc", "state": "on", "tags": ["a"], "score": null}`, nil},
+		{`{"id": 1.5, "name": "abc"}`, []string{"/id type"}},
+		{`{"id": 0, "name": "ABC"}`, []string{"/id minimum", "/name pattern"}},
+		{`{"name": "abcdefghi", "state": "dim"}`, []string{" required", "/name maxLength", "/state enum"}},
+		{`{"id": 1, "name": "a", "tags": ["a", 2, "c"], "score": 10}`, []string{"/score exclusiveMaximum", "/tags maxItems", "/tags/1 type"}},
+		{`{"id": 1, "name": "a", "extra": true}`, []string{"/extra false"}},
+		{`[]`, []string{" type"}},
+	}
+
+	for i, test := range tests {
+		err := s.Validate([]byte(test.doc))
+		if test.expected == nil {
+			if err != nil {
+				t.Errorf("%d: unexpected error %s", i, err)
+			}
+			continue
+		}
+		se, ok := err.(*SchemaError)
+		if !ok {
+			t.Fatalf("%d: (actual) %v != *SchemaError (expected)", i, err)
+		}
+		var actual []string
+		for _, v := range se.Violations {
+			actual = append(actual, v.Pointer+" "+v.Keyword)
+		}
+		if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, actual, test.expected)
+		}
+	}
+}
+
+func Test_Schema_Apply(t *testing.T) {
+	s, _ := ParseSchema(strings.NewReader(schemaDoc))
+	doc := []byte(`{"id": 1, "name": "abc", "tags": []}`)
+
+	p, _ := ParsePatch(strings.NewReader(`[
+		{"op": "add", "path": "/tags/-", "value": "x"},
+		{"op": "remove", "path": "/name"},
+		{"op": "replace", "path": "/id", "value": 2}
+	]`))
+	_, err := p.ApplyWithOptions(doc, &ApplyOptions{Schema: s})
+	se, ok := err.(*SchemaError)
+	if !ok || len(se.Violations) != 1 {
+		t.Fatalf("(actual) %v != one violation (expected)", err)
+	}
+	if v := se.Violations[0]; v.Keyword != "required" || v.Index != 1 {
+		t.Errorf("(actual) %s at %d != required at 1 (expected)", v.Keyword, v.Index)
+	}
+
+	p, _ = ParsePatch(strings.NewReader(`[{"op": "add", "path": "/tags/0", "value": 7}, {"op": "replace", "path": "/id", "value": 3}]`))
+	_, err = p.ApplyWithOptions(doc, &ApplyOptions{Schema: s})
+	se, ok = err.(*SchemaError)
+	if !ok || len(se.Violations) != 1 || se.Violations[0].Pointer != "/tags/0" || se.Violations[0].Index != 0 {
+		t.Errorf("(actual) %v != type violation at /tags/0 by 0 (expected)", err)
+	}
+
+	p, _ = ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/name", "value": "xyz"}]`))
+	if _, err := p.ApplyWithOptions(doc, &ApplyOptions{Schema: s}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func Test_Parser_Schema(t *testing.T) {
+	s, _ := ParseSchema(strings.NewReader(schemaDoc))
+	ps := &Parser{Schema: s}
+
+	tests := []struct {
+		patch    string
+		expected []string
+	}{
+		{`[{"op": "replace", "path": "/name", "value": "abc"}, {"op": "add", "path": "/tags/-", "value": "x"}, {"op": "remove", "path": "/state"}]`, nil},
+		{`[{"op": "replace", "path": "/id", "value": "7"}]`, []string{"0 /id type"}},
+		{`[{"op": "add", "path": "/tags", "value": ["a", 1]}]`, []string{"0 /tags/1 type"}},
+		{`[{"op": "test", "path": "/id", "value": 1}, {"op": "remove", "path": "/name"}]`, []string{"1 /name required"}},
+		{`[{"op": "move", "from": "/id", "path": "/state"}]`, []string{"0 /id required"}},
+		{`[{"op": "add", "path": "/name/-", "value": "x"}]`, []string{"0 /name/- type"}},
+		{`[{"op": "add", "path": "/other", "value": 1}, {"op": "add", "path": "/tags/0", "value": false}]`, []string{"0 /other false", "1 /tags/0 type"}},
+	}
+
+	for i, test := range tests {
+		_, err := ps.Parse(strings.NewReader(test.patch))
+		if test.expected == nil {
+			if err != nil {
+				t.Errorf("%d: unexpected error %s", i, err)
+			}
+			continue
+		}
+		se, ok := err.(*SchemaError)
+		if !ok {
+			t.Fatalf("%d: (actual) %v != *SchemaError (expected)", i, err)
+		}
+		var actual []string
+		for _, v := range se.Violations {
+			actual = append(actual, fmt.Sprintf("%d %s %s", v.Index, v.Pointer, v.Keyword))
+		}
+		if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%d: (actual) %q != %q (expected)", i, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package rfc6902
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrorNoVersion is returned for a version a Store does not hold.
+	ErrorNoVersion = errors.New("rfc6902: no such version")
+	// ErrorCorruptHistory is returned by Store.Verify when the stored
+	// patches do not replay to the stored documents.
+	ErrorCorruptHistory = errors.New("rfc6902: history does not replay")
+)
+
+/*
+A Store keeps the versions of a JSON document as a base snapshot and a log of
+the patches applied to it.  Version 0 is the document the store was created
+with, every patch appended makes the next version.
+*/
+type Store interface {
+	// Version returns the latest version.
+	Version() (int, error)
+	// Get returns the document at version.
+	Get(version int) ([]byte, error)
+	// Patch returns the patch that made version from the version before.
+	Patch(version int) (*Patcher, error)
+	// Append applies p to the latest version and returns the new version.
+	Append(p *Patcher) (int, error)
+	// Compact discards the history before version, which becomes the base
+	// snapshot.
+	Compact(version int) error
+	// Verify checks that every stored patch still applies and leads to the
+	// stored documents.
+	Verify() error
+}
+
+/*
+MemoryStore is a Store held in memory.
+
+Besides each patch the store keeps its inverse, the patch going back to the
+previous version.  Get replays patches forward from the nearest snapshot at
+or before the version, or inverse patches backward from the nearest later
+snapshot or the latest version, whichever is fewer.  With SnapshotEvery set
+the document is snapshotted every SnapshotEvery versions.
+*/
+type MemoryStore struct {
+	// Options customise applying appended patches, may be nil.  Patches are
+	// replayed with the same options, except for BeforeOp and AfterOp, so
+	// they must not change afterwards.
+	Options *ApplyOptions
+	// SnapshotEvery is the number of versions between snapshots, none when
+	// 0.
+	SnapshotEvery int
+
+	mu   sync.Mutex
+	log  []entry // log[0] is the base snapshot
+	head interface{}
+	j    journal
+}
+
+// entry is a version in the log.
+type entry struct {
+	Version  int             `json:"version"`
+	Patch    *Patcher        `json:"patch,omitempty"`
+	Inverse  *Patcher        `json:"inverse,omitempty"`
+	Snapshot json.RawMessage `json:"snapshot,omitempty"`
+}
+
+// journal persists the log of a MemoryStore.
+type journal interface {
+	append(r entry) error
+	rewrite(log []entry) error
+}
+
+// NewMemoryStore returns a store holding doc as version 0.
+func NewMemoryStore(doc []byte) (*MemoryStore, error) {
+	var v interface{}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	snapshot, _ := json.Marshal(v)
+	return &MemoryStore{log: []entry{{Snapshot: snapshot}}, head: v}, nil
+}
+
+func (s *MemoryStore) Version() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version(), nil
+}
+
+func (s *MemoryStore) version() int {
+	return s.log[len(s.log)-1].Version
+}
+
+// index returns the position of version in the log.
+func (s *MemoryStore) index(version int) (int, error) {
+	i := version - s.log[0].Version
+	if i < 0 || i >= len(s.log) {
+		return 0, ErrorNoVersion
+	}
+	return i, nil
+}
+
+func (s *MemoryStore) Get(version int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := s.at(version)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// at reconstructs the document at version.
+func (s *MemoryStore) at(version int) (interface{}, error) {
+	i, err := s.index(version)
+	if err != nil {
+		return nil, err
+	}
+	lo := i
+	for s.log[lo].Snapshot == nil {
+		lo--
+	}
+	hi := len(s.log) - 1
+	for j := i; j < hi; j++ {
+		if s.log[j].Snapshot != nil {
+			hi = j
+			break
+		}
+	}
+
+	var v interface{}
+	if hi-i < i-lo {
+		if s.log[hi].Snapshot == nil {
+			v = clone(s.head)
+		} else if err = json.Unmarshal(s.log[hi].Snapshot, &v); err != nil {
+			return nil, err
+		}
+		for j := hi; j > i; j-- {
+			if v, err = s.log[j].Inverse.apply(v); err != nil {
+				return nil, fmt.Errorf("rfc6902: inverse of version %d: %s", s.log[j].Version, err)
+			}
+		}
+		return v, nil
+	}
+	if err = json.Unmarshal(s.log[lo].Snapshot, &v); err != nil {
+		return nil, err
+	}
+	for j := lo + 1; j <= i; j++ {
+		if v, err = s.log[j].Patch.applyWith(v, s.replay()); err != nil {
+			return nil, fmt.Errorf("rfc6902: version %d: %s", s.log[j].Version, err)
+		}
+	}
+	return v, nil
+}
+
+// replay returns the options patches are replayed with: Options without the
+// hooks, which only see a patch when it is appended.
+func (s *MemoryStore) replay() *ApplyOptions {
+	if s.Options == nil {
+		return nil
+	}
+	o := *s.Options
+	o.BeforeOp, o.AfterOp = nil, nil
+	return &o
+}
+
+func (s *MemoryStore) Patch(version int) (*Patcher, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.index(version)
+	if err != nil || i == 0 {
+		return nil, ErrorNoVersion
+	}
+	return s.log[i].Patch, nil
+}
+
+// Inverse returns the patch that goes back from version to the version
+// before.
+func (s *MemoryStore) Inverse(version int) (*Patcher, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.index(version)
+	if err != nil || i == 0 {
+		return nil, ErrorNoVersion
+	}
+	return s.log[i].Inverse, nil
+}
+
+func (s *MemoryStore) Append(p *Patcher) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, err := p.applyWith(clone(s.head), s.Options)
+	if err != nil {
+		return 0, err
+	}
+	r := entry{
+		Version: s.version() + 1,
+		Patch:   p,
+		Inverse: &Patcher{ops: diff(jsonptr{}, v, s.head)},
+	}
+	if s.SnapshotEvery > 0 && r.Version%s.SnapshotEvery == 0 {
+		if r.Snapshot, err = json.Marshal(v); err != nil {
+			return 0, err
+		}
+	}
+	if s.j != nil {
+		if err = s.j.append(r); err != nil {
+			return 0, err
+		}
+	}
+	s.log = append(s.log, r)
+	s.head = v
+	return r.Version, nil
+}
+
+func (s *MemoryStore) Compact(version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.index(version)
+	if err != nil {
+		return err
+	}
+	v, err := s.at(version)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	log := append([]entry{{Version: version, Snapshot: snapshot}}, s.log[i+1:]...)
+	if s.j != nil {
+		if err = s.j.rewrite(log); err != nil {
+			return err
+		}
+	}
+	s.log = log
+	return nil
+}
+
+func (s *MemoryStore) Verify() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var v interface{}
+	if err := json.Unmarshal(s.log[0].Snapshot, &v); err != nil {
+		return err
+	}
+	for _, r := range s.log[1:] {
+		next, err := r.Patch.applyWith(clone(v), s.replay())
+		if err != nil {
+			return fmt.Errorf("%w: version %d: %s", ErrorCorruptHistory, r.Version, err)
+		}
+		if back, err := r.Inverse.apply(clone(next)); err != nil || !reflect.DeepEqual(back, v) {
+			return fmt.Errorf("%w: inverse of version %d", ErrorCorruptHistory, r.Version)
+		}
+		if r.Snapshot != nil {
+			var snapshot interface{}
+			if err := json.Unmarshal(r.Snapshot, &snapshot); err != nil || !reflect.DeepEqual(snapshot, next) {
+				return fmt.Errorf("%w: snapshot of version %d", ErrorCorruptHistory, r.Version)
+			}
+		}
+		v = next
+	}
+	if !reflect.DeepEqual(v, s.head) {
+		return fmt.Errorf("%w: version %d", ErrorCorruptHistory, s.version())
+	}
+	return nil
+}
+
+/*
+FileStore is a MemoryStore kept in a file, one JSON entry per line: the base
+snapshot followed by an entry for every version appended, holding the patch,
+its inverse and the snapshot if one was taken.  Appending writes a line,
+compacting rewrites the file.
+*/
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// CreateFileStore creates the file path for a store holding doc as version
+// 0.  The file must not exist.
+func CreateFileStore(path string, doc []byte) (*FileStore, error) {
+	ms, err := NewMemoryStore(doc)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	fs := &FileStore{ms, path}
+	if err = fs.rewrite(ms.log); err != nil {
+		return nil, err
+	}
+	ms.j = fs
+	return fs, nil
+}
+
+// OpenFileStore reads the store kept in the file path.  Its patches are
+// parsed with ps, ParsePatch is used when nil, and replayed with o, the
+// options they were appended with.
+func OpenFileStore(path string, ps *Parser, o *ApplyOptions) (*FileStore, error) {
+	if ps == nil {
+		ps = new(Parser)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var log []entry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	for sc.Scan() {
+		var line struct {
+			entry
+			Patch   json.RawMessage `json:"patch"`
+			Inverse json.RawMessage `json:"inverse"`
+		}
+		if err = json.Unmarshal(sc.Bytes(), &line); err != nil {
+			return nil, err
+		}
+		r := line.entry
+		if len(log) > 0 {
+			if r.Version != log[len(log)-1].Version+1 {
+				return nil, fmt.Errorf("%w: version %d follows %d", ErrorCorruptHistory, r.Version, log[len(log)-1].Version)
+			}
+			if r.Patch, err = ps.Parse(bytes.NewReader(line.Patch)); err != nil {
+				return nil, err
+			}
+			if r.Inverse, err = ParsePatch(bytes.NewReader(line.Inverse)); err != nil {
+				return nil, err
+			}
+		} else if r.Snapshot == nil {
+			return nil, fmt.Errorf("%w: no base snapshot", ErrorCorruptHistory)
+		}
+		log = append(log, r)
+	}
+	if len(log) == 0 {
+		return nil, fmt.Errorf("%w: no base snapshot", ErrorCorruptHistory)
+	}
+
+	// the latest version is replayed from the last snapshot
+	ms := &MemoryStore{Options: o, log: log}
+	last := len(log) - 1
+	for log[last].Snapshot == nil {
+		last--
+	}
+	if err = json.Unmarshal(log[last].Snapshot, &ms.head); err != nil {
+		return nil, err
+	}
+	for _, r := range log[last+1:] {
+		if ms.head, err = r.Patch.applyWith(ms.head, ms.replay()); err != nil {
+			return nil, fmt.Errorf("%w: version %d: %s", ErrorCorruptHistory, r.Version, err)
+		}
+	}
+	fs := &FileStore{ms, path}
+	ms.j = fs
+	return fs, nil
+}
+
+func (fs *FileStore) append(r entry) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewrite replaces the file with log, through a temporary file renamed over
+// it.
+func (fs *FileStore) rewrite(log []entry) error {
+	var buf bytes.Buffer
+	for _, r := range log {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(b, '\n'))
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
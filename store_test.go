@@ -0,0 +1,161 @@
+package rfc6902
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var storeVersions = []struct {
+	patch, doc string
+}{
+	{``, `{"a": 1}`},
+	{`[{"op": "add", "path": "/b", "value": [1]}]`, `{"a": 1, "b": [1]}`},
+	{`[{"op": "add", "path": "/b/-", "value": 2}]`, `{"a": 1, "b": [1, 2]}`},
+	{`[{"op": "remove", "path": "/a"}]`, `{"b": [1, 2]}`},
+	{`[{"op": "move", "from": "/b/0", "path": "/c"}]`, `{"b": [2], "c": 1}`},
+	{`[{"op": "replace", "path": "/c", "value": {"d": true}}]`, `{"b": [2], "c": {"d": true}}`},
+	{`[{"op": "add", "path": "/e", "value": {"n": 1}}, {"op": "remove", "path": "/e/n"}]`, `{"b": [2], "c": {"d": true}, "e": {}}`},
+	{`[{"op": "add", "path": "/f", "value": null}]`, `{"b": [2], "c": {"d": true}, "e": {}, "f": null}`},
+	{`[{"op": "remove", "path": "/f"}]`, `{"b": [2], "c": {"d": true}, "e": {}}`},
+}
+
+// fill appends the patches of storeVersions to s.
+func fill(t *testing.T, s Store) {
+	for i, v := range storeVersions[1:] {
+		p, _ := ParsePatch(strings.NewReader(v.patch))
+		version, err := s.Append(p)
+		if err != nil {
+			t.Fatalf("%d: Append failed: %s", i, err)
+		}
+		if version != i+1 {
+			t.Errorf("%d: version (actual) %d != %d (expected)", i, version, i+1)
+		}
+	}
+}
+
+// checkVersions checks that s returns the documents of storeVersions from
+// version first on.
+func checkVersions(t *testing.T, s Store, first int) {
+	for i := first; i < len(storeVersions); i++ {
+		actual, err := s.Get(i)
+		if err != nil {
+			t.Errorf("%d: Get failed: %s", i, err)
+			continue
+		}
+		if !jsonEqual(actual, []byte(storeVersions[i].doc)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, storeVersions[i].doc)
+		}
+	}
+	if err := s.Verify(); err != nil {
+		t.Errorf("Verify failed: %s", err)
+	}
+}
+
+func Test_MemoryStore(t *testing.T) {
+	for _, every := range []int{0, 1, 2} {
+		s, err := NewMemoryStore([]byte(storeVersions[0].doc))
+		if err != nil {
+			t.Fatalf("NewMemoryStore failed: %s", err)
+		}
+		s.SnapshotEvery = every
+		fill(t, s)
+		checkVersions(t, s, 0)
+
+		if _, err := s.Get(len(storeVersions)); err != ErrorNoVersion {
+			t.Errorf("%d: expected ErrorNoVersion, got %v", every, err)
+		}
+		if _, err := s.Patch(0); err != ErrorNoVersion {
+			t.Errorf("%d: expected ErrorNoVersion for the base", every)
+		}
+
+		inv, _ := s.Inverse(3)
+		actual, err := inv.Apply([]byte(storeVersions[3].doc))
+		if err != nil || !jsonEqual(actual, []byte(storeVersions[2].doc)) {
+			t.Errorf("%d: inverse (actual) %s != %s (expected)", every, actual, storeVersions[2].doc)
+		}
+
+		if err := s.Compact(2); err != nil {
+			t.Fatalf("%d: Compact failed: %s", every, err)
+		}
+		if _, err := s.Get(1); err != ErrorNoVersion {
+			t.Errorf("%d: expected ErrorNoVersion after compaction, got %v", every, err)
+		}
+		checkVersions(t, s, 2)
+	}
+
+	// a failing patch is not recorded
+	s, _ := NewMemoryStore([]byte(`{}`))
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "remove", "path": "/x"}]`))
+	if _, err := s.Append(p); err == nil {
+		t.Errorf("expected an error")
+	}
+	if v, _ := s.Version(); v != 0 {
+		t.Errorf("version (actual) %d != 0 (expected)", v)
+	}
+}
+
+func Test_MemoryStore_Verify(t *testing.T) {
+	s, _ := NewMemoryStore([]byte(storeVersions[0].doc))
+	fill(t, s)
+	// a patch that no longer applies to the version before it
+	s.log[2].Patch, _ = ParsePatch(strings.NewReader(`[{"op": "test", "path": "/a", "value": 2}]`))
+	if err := s.Verify(); !errors.Is(err, ErrorCorruptHistory) {
+		t.Errorf("expected ErrorCorruptHistory, got %v", err)
+	}
+}
+
+func Test_MemoryStore_Hooks(t *testing.T) {
+	s, _ := NewMemoryStore([]byte(storeVersions[0].doc))
+	calls := 0
+	s.Options = &ApplyOptions{AfterOp: func(int, Operation, interface{}, interface{}) error {
+		calls++
+		return nil
+	}}
+	fill(t, s)
+	expected := 0
+	for _, v := range storeVersions[1:] {
+		expected += strings.Count(v.patch, `"op"`)
+	}
+	checkVersions(t, s, 0)
+	if calls != expected {
+		t.Errorf("hook calls (actual) %d != %d (expected)", calls, expected)
+	}
+}
+
+func Test_FileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.log")
+	fs, err := CreateFileStore(path, []byte(storeVersions[0].doc))
+	if err != nil {
+		t.Fatalf("CreateFileStore failed: %s", err)
+	}
+	fs.SnapshotEvery = 2
+	fill(t, fs)
+	if _, err := CreateFileStore(path, []byte(`{}`)); err == nil {
+		t.Errorf("expected an error creating an existing store")
+	}
+
+	reopened, err := OpenFileStore(path, nil, nil)
+	if err != nil {
+		t.Fatalf("OpenFileStore failed: %s", err)
+	}
+	checkVersions(t, reopened, 0)
+
+	if err = reopened.Compact(3); err != nil {
+		t.Fatalf("Compact failed: %s", err)
+	}
+	if reopened, err = OpenFileStore(path, nil, nil); err != nil {
+		t.Fatalf("OpenFileStore failed: %s", err)
+	}
+	checkVersions(t, reopened, 3)
+	if b, _ := os.ReadFile(path); strings.Count(string(b), "\n") != len(storeVersions)-3 {
+		t.Errorf("compacted file has %d lines: %s", strings.Count(string(b), "\n"), b)
+	}
+
+	os.WriteFile(path, []byte(`{"version": 0, "snapshot": {}}`+"\n"+`{"version": 2, "patch": [], "inverse": []}`+"\n"), 0644)
+	if _, err = OpenFileStore(path, nil, nil); !errors.Is(err, ErrorCorruptHistory) {
+		t.Errorf("expected ErrorCorruptHistory, got %v", err)
+	}
+}
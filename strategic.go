@@ -0,0 +1,225 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+StrategicMergePatch applies a strategic merge patch to the JSON document doc.
+
+A strategic merge patch is a JSON Merge Patch (RFC 7396) that merges arrays
+of objects element by element, like the one of Kubernetes.  keys names the
+merge key of those arrays, as for keyed addressing, so with
+Keys{"/friends": "id"} the elements of the friends array are merged with the
+element of the same id, or appended when there is none.  Other arrays are
+replaced.
+
+The patch may contain directives:
+
+	{"$patch": "replace"}        in an object: replace the object instead of
+	                             merging it; as an element of a keyed array:
+	                             replace the array with the other elements
+	{"$patch": "delete"}         in an object: remove the object; as an
+	                             element of a keyed array, with its key:
+	                             remove the element of that key
+	"$setElementOrder/name": []  order the array name like the given list, of
+	                             objects holding just the key for keyed arrays
+	                             and of values otherwise.  Elements missing
+	                             from the list follow in their current order.
+	"$deleteFromPrimitiveList/name": []
+	                             remove the given values from the array name
+*/
+func StrategicMergePatch(doc, patch []byte, keys Keys) ([]byte, error) {
+	_, after, err := strategic(doc, patch, keys)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(after)
+}
+
+// StrategicMergeToPatch returns the JSON Patch doing what the strategic merge
+// patch does to doc, to be applied with ApplyOptions.Keys set to keys.
+// Elements of keyed arrays are addressed by key.
+func StrategicMergeToPatch(doc, patch []byte, keys Keys) (*Patcher, error) {
+	before, after, err := strategic(doc, patch, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Patcher{ops: diffWith(jsonptr{}, before, after, keys)}, nil
+}
+
+func strategic(doc, patch []byte, keys Keys) (before, after interface{}, err error) {
+	if len(doc) <= 0 || len(patch) <= 0 {
+		return nil, nil, errors.New("rfc6902: empty JSON document")
+	}
+	var p interface{}
+	if err = json.Unmarshal(doc, &before); err != nil {
+		return nil, nil, err
+	}
+	if err = json.Unmarshal(patch, &p); err != nil {
+		return nil, nil, err
+	}
+	after, err = strategicMerge(jsonptr{}, clone(before), p, keys)
+	return before, after, err
+}
+
+// strategicMerge merges patch into target at path.  target is modified in
+// place.
+func strategicMerge(path jsonptr, target, patch interface{}, keys Keys) (interface{}, error) {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return clone(patch), nil
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	switch p["$patch"] {
+	case nil, "merge":
+	case "replace":
+		t = make(map[string]interface{})
+	case "delete":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("rfc6902: unknown directive %v at %q", p["$patch"], path.path())
+	}
+
+	for _, name := range sortedKeys(p) {
+		if strings.HasPrefix(name, "$") {
+			continue
+		}
+		value := p[name]
+		if o, ok := value.(map[string]interface{}); value == nil || (ok && o["$patch"] == "delete") {
+			delete(t, name)
+			continue
+		}
+		child := path.child(reftoken(encode(name)))
+		var err error
+		if m := keys.member(child); m != "" {
+			if a, ok := value.([]interface{}); ok {
+				old, _ := t[name].([]interface{})
+				if t[name], err = mergeList(child, old, a, m, keys); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		if t[name], err = strategicMerge(child, t[name], value, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range sortedKeys(p) {
+		var directive, field string
+		if i := strings.IndexByte(name, '/'); strings.HasPrefix(name, "$") && i > 0 {
+			directive, field = name[:i], name[i+1:]
+		} else {
+			continue
+		}
+		list, ok := p[name].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rfc6902: %s needs an array at %q", directive, path.path())
+		}
+		if _, ok := t[field]; !ok {
+			continue
+		}
+		a, ok := t[field].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rfc6902: %s applied to a non-array at %q", directive, path.path())
+		}
+		switch directive {
+		case "$setElementOrder":
+			t[field] = reorder(a, list, keys.member(path.child(reftoken(encode(field)))))
+		case "$deleteFromPrimitiveList":
+			kept := make([]interface{}, 0, len(a))
+			for _, e := range a {
+				if indexOf(list, e) < 0 {
+					kept = append(kept, e)
+				}
+			}
+			t[field] = kept
+		default:
+			return nil, fmt.Errorf("rfc6902: unknown directive %s at %q", directive, path.path())
+		}
+	}
+	return t, nil
+}
+
+// mergeList merges the elements of patch into target, matching them by the
+// member m.
+func mergeList(path jsonptr, target, patch []interface{}, m string, keys Keys) ([]interface{}, error) {
+	a := make([]interface{}, 0, len(target)+len(patch))
+	for _, e := range patch {
+		if o, ok := e.(map[string]interface{}); ok && o["$patch"] == "replace" {
+			target = nil
+		}
+	}
+	a = append(a, target...)
+
+	for _, e := range patch {
+		o, _ := e.(map[string]interface{})
+		if o != nil && o["$patch"] == "replace" {
+			continue
+		}
+		k, ok := keyOf(e, m)
+		if !ok {
+			return nil, fmt.Errorf("rfc6902: element without merge key %q at %q", m, path.path())
+		}
+		i := keyIndex(a, m, k)
+		if o != nil && o["$patch"] == "delete" {
+			if i >= 0 {
+				a = append(a[:i], a[i+1:]...)
+			}
+			continue
+		}
+		var old interface{}
+		if i < 0 {
+			i = len(a)
+			a = append(a, nil)
+		} else {
+			old = a[i]
+		}
+		v, err := strategicMerge(path.child(indexToken(i)), old, e, keys)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+// reorder returns the elements of a in the order of the list, matching them
+// by the member m or, when m is empty, by value.
+func reorder(a, order []interface{}, m string) []interface{} {
+	out := make([]interface{}, 0, len(a))
+	used := make([]bool, len(a))
+	for _, o := range order {
+		for i, e := range a {
+			if used[i] {
+				continue
+			}
+			var same bool
+			if m == "" {
+				same = sameValue(e, o)
+			} else {
+				k, ok := keyOf(e, m)
+				ko, oko := keyOf(o, m)
+				same = ok && oko && k == ko
+			}
+			if same {
+				out = append(out, e)
+				used[i] = true
+				break
+			}
+		}
+	}
+	for i, e := range a {
+		if !used[i] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package rfc6902
+
+import (
+	"testing"
+)
+
+func Test_StrategicMergePatch(t *testing.T) {
+	doc := `{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["x", "y"]}, {"id": 2, "name": "c"}], "meta": {"k": 1, "l": 2}}`
+	keys := Keys{"/friends": "id"}
+	tests := []struct {
+		patch, expected string
+	}{
+		// elements are merged by key, new ones appended
+		{`{"friends": [{"id": 2, "name": "C"}, {"id": 3, "name": "d"}]}`,
+			`{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["x", "y"]}, {"id": 2, "name": "C"}, {"id": 3, "name": "d"}], "meta": {"k": 1, "l": 2}}`},
+		// arrays without a merge key are replaced
+		{`{"friends": [{"id": 1, "tags": ["z"]}]}`,
+			`{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["z"]}, {"id": 2, "name": "c"}], "meta": {"k": 1, "l": 2}}`},
+		{`{"friends": [{"id": 1, "$patch": "delete"}], "name": null}`,
+			`{"friends": [{"id": 2, "name": "c"}], "meta": {"k": 1, "l": 2}}`},
+		{`{"friends": [{"$patch": "replace"}, {"id": 3}]}`,
+			`{"name": "a", "friends": [{"id": 3}], "meta": {"k": 1, "l": 2}}`},
+		{`{"meta": {"$patch": "replace", "m": 3}}`,
+			`{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["x", "y"]}, {"id": 2, "name": "c"}], "meta": {"m": 3}}`},
+		{`{"meta": {"$patch": "delete"}}`,
+			`{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["x", "y"]}, {"id": 2, "name": "c"}]}`},
+		{`{"$setElementOrder/friends": [{"id": 3}, {"id": 2}], "friends": [{"id": 3}]}`,
+			`{"name": "a", "friends": [{"id": 3}, {"id": 2, "name": "c"}, {"id": 1, "name": "b", "tags": ["x", "y"]}], "meta": {"k": 1, "l": 2}}`},
+		{`{"friends": [{"id": 1, "$setElementOrder/tags": ["y", "x"], "$deleteFromPrimitiveList/tags": ["z"]}]}`,
+			`{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["y", "x"]}, {"id": 2, "name": "c"}], "meta": {"k": 1, "l": 2}}`},
+		// new objects lose their directives
+		{`{"extra": {"$patch": "replace", "a": {"b": null}}}`,
+			`{"name": "a", "friends": [{"id": 1, "name": "b", "tags": ["x", "y"]}, {"id": 2, "name": "c"}], "meta": {"k": 1, "l": 2}, "extra": {"a": {}}}`},
+	}
+
+	for i, test := range tests {
+		actual, err := StrategicMergePatch([]byte(doc), []byte(test.patch), keys)
+		if err != nil {
+			t.Errorf("%d: StrategicMergePatch failed: %s", i, err)
+			continue
+		}
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+
+		p, err := StrategicMergeToPatch([]byte(doc), []byte(test.patch), keys)
+		if err != nil {
+			t.Errorf("%d: StrategicMergeToPatch failed: %s", i, err)
+			continue
+		}
+		actual, err = p.ApplyWithOptions([]byte(doc), &ApplyOptions{Keys: keys})
+		if err != nil {
+			t.Errorf("%d: ApplyWithOptions failed: %s", i, err)
+			continue
+		}
+		if !jsonEqual(actual, []byte(test.expected)) {
+			t.Errorf("%d: patch (actual) %s != %s (expected)", i, actual, test.expected)
+		}
+	}
+
+	for i, patch := range []string{
+		`{"friends": [{"name": "x"}]}`,
+		`{"meta": {"$patch": "bogus"}}`,
+		`{"$setElementOrder/friends": {"id": 1}}`,
+		`{"$setElementOrder/name": ["a"]}`,
+		`{"$unknown/friends": []}`,
+	} {
+		if _, err := StrategicMergePatch([]byte(doc), []byte(patch), keys); err == nil {
+			t.Errorf("%d: expected an error for %s", i, patch)
+		}
+	}
+}
+
+func Test_StrategicMergeToPatch_Keyed(t *testing.T) {
+	p, err := StrategicMergeToPatch([]byte(`{"friends": [{"id": 1, "v": 0}, {"id": 2, "v": 0}]}`), []byte(`{"friends": [{"id": 2, "v": 1}]}`), Keys{"/friends": "id"})
+	if err != nil {
+		t.Fatalf("StrategicMergeToPatch failed: %s", err)
+	}
+	actual, _ := p.MarshalJSON()
+	expected := `[{"op": "replace", "path": "/friends/[id=2]/v", "value": 1}]`
+	if !jsonEqual(actual, []byte(expected)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, expected)
+	}
+}
@@ -0,0 +1,477 @@
+package rfc6902
+
+import (
+	"strconv"
+)
+
+// TieBreak selects which of two concurrent patches wins when both write the
+// same location.
+type TieBreak int
+
+const (
+	// PreferA lets the first patch passed to Transform win.
+	PreferA TieBreak = iota
+	// PreferB lets the second patch passed to Transform win.
+	PreferB
+)
+
+/*
+Transformer rebases concurrent patches made against the same document version
+(operational transformation).
+
+Array indices are rebased across concurrent inserts and removes, operations
+below a location the other patch removed or replaced are dropped, and a move
+carries concurrent edits of the moved value along to its new location.  When
+both patches replace the same location, or insert at the same array index,
+Tie decides which one wins or goes first.
+
+A move whose value the other patch removes or overwrites, or moves into the
+moved value, is reduced to the removal of its source and of what its path
+held: the value is lost on both sides.  A copy whose source the other patch
+changes is dropped the same way.  Non-standard operations, extended or
+registered with a Parser, are assumed to change the value at their path in
+place.  Their effect cannot be combined with a concurrent change of that
+value, a value within it or a value it is part of, nor with a copy of it, so
+they are reduced to the removal of the value at their path as well.  When both patches move the same value it
+goes where the winning move puts it, a move to the root always wins.
+
+Tokens that are array indices ("-" or a number) are assumed to address array
+elements, everything else object members.  Concurrent appends using "-" are
+both kept but end up in the order the patches were applied, and a value moved
+or copied to "-" cannot be taken back when it is lost, so patches using "-"
+may not converge.
+*/
+type Transformer struct {
+	Tie TieBreak
+}
+
+// Transform rebases a and b using the PreferA tie-breaking policy.
+func Transform(a, b *Patcher) (a2, b2 *Patcher) {
+	return new(Transformer).Transform(a, b)
+}
+
+// Transform returns a2, which applies a on top of b, and b2, which applies b
+// on top of a, so that applying a then b2 yields the same document as applying
+// b then a2.
+func (t *Transformer) Transform(a, b *Patcher) (a2, b2 *Patcher) {
+	as, bs := transform(a.ops, b.ops, t.Tie == PreferA)
+	return &Patcher{ops: as}, &Patcher{ops: bs}
+}
+
+// transform returns xs as they must be applied after ys and ys as they must
+// be applied after xs.  The arguments are not modified.
+func transform(xs, ys []Operation, xWins bool) ([]Operation, []Operation) {
+	switch {
+	case len(xs) == 0 || len(ys) == 0:
+		return xs[:len(xs):len(xs)], ys[:len(ys):len(ys)]
+	case len(xs) > 1:
+		x1, ys1 := transform(xs[:1], ys, xWins)
+		x2, ys2 := transform(xs[1:], ys1, xWins)
+		return append(x1, x2...), ys2
+	case len(ys) > 1:
+		x1, y1 := transform(xs, ys[:1], xWins)
+		x2, y2 := transform(x1, ys[1:], xWins)
+		return x2, append(y1, y2...)
+	}
+
+	x, y := xs[0], ys[0]
+	if x.Op == "move" && y.Op == "move" && x.From == y.From {
+		// the value is moved once, a move to the root wins
+		if (x.Path == "") != (y.Path == "") {
+			xWins = x.Path == ""
+		}
+		if xWins {
+			return claim(x, y)
+		}
+		ys, xs = claim(y, x)
+		return xs, ys
+	}
+	xLost, yLost := lost(x, y), lost(y, x)
+	switch {
+	case xLost && yLost:
+		xUndo, xs := degrade(x)
+		yUndo, ys := degrade(y)
+		xs, ys = transform(xs, ys, xWins)
+		return append(yUndo, xs...), append(xUndo, ys...)
+	case xLost:
+		undo, xs := degrade(x)
+		xs, ys = transform(xs, ys, xWins)
+		return xs, append(undo, ys...)
+	case yLost:
+		undo, ys := degrade(y)
+		xs, ys = transform(xs, ys, xWins)
+		return append(undo, xs...), ys
+	}
+
+	xs, ys = nil, nil
+	if x2, ok := rebase(x, y, xWins); ok {
+		xs = []Operation{x2}
+	}
+	if y2, ok := rebase(y, x, !xWins); ok {
+		ys = []Operation{y2}
+	}
+	return xs, ys
+}
+
+/*
+claim returns the move w as it applies after the move l of the same value,
+and l after w.  The value ends up where w moves it; all that is left of l is
+the removal of what its path held before.
+*/
+func claim(w, l Operation) ([]Operation, []Operation) {
+	if w.Path == l.Path {
+		return nil, nil
+	}
+	// both paths are relative to the document without the value
+	var cleared []Operation
+	if to, _ := newJSONPointer(l.Path); !isIndex(to[len(to)-1]) {
+		cleared = []Operation{{Op: "add", Path: l.Path}, {Op: "remove", Path: l.Path}}
+	}
+	ws, ls := transform([]Operation{{Op: "add", Path: w.Path}}, cleared, true)
+	if len(ws) == 0 {
+		// the path of w is gone, so is the value
+		return []Operation{{Op: "remove", Path: l.Path}}, ls
+	}
+	return []Operation{{Op: "move", From: l.Path, Path: ws[0].Path}}, ls
+}
+
+// lost reports whether x moves a value y removes or overwrites, or moves it
+// into the value y moves into it, so that the value cannot be moved after y.
+// A copy is lost when y changes the value at all, a non-standard operation
+// when y touches its value in any way.
+func lost(x, y Operation) bool {
+	xLocs, yLocs := x.locations(), y.locations()
+	if xLocs == nil || yLocs == nil {
+		return false
+	}
+	if custom(x.Op) {
+		at := xLocs[0]
+		if _, pred := predicates[y.Op]; pred || y.Op == "test" {
+			return false
+		}
+		if y.Op == "move" {
+			if nested(at, yLocs[1]) {
+				return true
+			}
+			// the path of a move is relative to the document without its source
+			rest, _ := shift(at, targetRole, x.Op, removal(yLocs[1], y.Op), true)
+			return nested(rest, yLocs[0])
+		}
+		for _, l := range yLocs {
+			if nested(at, l) {
+				return true
+			}
+		}
+		return false
+	}
+	if x.Op != "move" && x.Op != "copy" {
+		return false
+	}
+	from := xLocs[1]
+	if x.Op == "copy" {
+		return changes(y, yLocs, from)
+	}
+	switch y.Op {
+	case "remove":
+		return below(from, yLocs[0]) || equal(from, yLocs[0])
+	case "replace":
+		return below(from, yLocs[0])
+	case "add", "copy":
+		return overwrites(yLocs[0], from)
+	case "move":
+		if below(from, yLocs[1]) || equal(from, yLocs[1]) {
+			// the value is carried along
+			return false
+		}
+		rest, _ := shift(from, targetRole, x.Op, removal(yLocs[1], y.Op), true)
+		if overwrites(yLocs[0], rest) {
+			return true
+		}
+		x2, ok := rebase(x, y, true)
+		return ok && x2.Op == "move" && below(x2.locations()[0], x2.locations()[1])
+	}
+	return false
+}
+
+// changes reports whether y changes the value at ptr, other than by moving
+// it as a whole.
+func changes(y Operation, yLocs []jsonptr, ptr jsonptr) bool {
+	switch y.Op {
+	case "remove", "replace":
+		at := yLocs[0]
+		return below(at, ptr) || equal(at, ptr) || below(ptr, at)
+	case "add", "copy":
+		return below(yLocs[0], ptr) || overwrites(yLocs[0], ptr)
+	case "move":
+		from := yLocs[1]
+		if below(ptr, from) || equal(ptr, from) {
+			return false
+		}
+		if below(from, ptr) {
+			return true
+		}
+		rest, _ := shift(ptr, targetRole, "copy", removal(from, y.Op), true)
+		return below(yLocs[0], rest) || overwrites(yLocs[0], rest)
+	}
+	return false
+}
+
+// overwrites reports whether adding a value at at replaces ptr or one of
+// its ancestors.
+func overwrites(at, ptr jsonptr) bool {
+	if len(at) > 0 && isIndex(at[len(at)-1]) {
+		return false
+	}
+	return equal(at, ptr) || below(ptr, at)
+}
+
+/*
+degrade returns the operations a move, copy or non-standard operation is
+reduced to when its value is lost: the removal of the source of a move and of
+whatever its path held before.  undo removes the value from its path again,
+turning the document the operation made into the one the reduced operations
+make.
+*/
+func degrade(x Operation) (undo, reduced []Operation) {
+	to, _ := newJSONPointer(x.Path)
+	if custom(x.Op) {
+		// the value was changed in place, not added
+		switch {
+		case len(to) == 0:
+			undo = []Operation{{Op: "replace", Path: x.Path}}
+		case isIndex(to[len(to)-1]):
+			undo = []Operation{{Op: "remove", Path: x.Path}}
+		default:
+			undo = []Operation{{Op: "add", Path: x.Path}, {Op: "remove", Path: x.Path}}
+		}
+		return undo, undo
+	}
+	if x.Op == "move" {
+		reduced = []Operation{{Op: "remove", Path: x.From}}
+	}
+	switch {
+	case len(to) == 0:
+		undo = []Operation{{Op: "replace", Path: x.Path}}
+		reduced = append(reduced, undo...)
+	case isIndex(to[len(to)-1]):
+		undo = []Operation{{Op: "remove", Path: x.Path}}
+	default:
+		undo = []Operation{{Op: "remove", Path: x.Path}}
+		reduced = append(reduced, Operation{Op: "add", Path: x.Path}, Operation{Op: "remove", Path: x.Path})
+	}
+	return undo, reduced
+}
+
+// custom reports whether op is a non-standard operation other than a
+// predicate, which only reads its path.
+func custom(op string) bool {
+	switch op {
+	case "add", "remove", "replace", "move", "copy", "test":
+		return false
+	}
+	_, pred := predicates[op]
+	return !pred
+}
+
+// nested reports whether x and y are the same location or one lies below the
+// other.
+func nested(x, y jsonptr) bool {
+	n := commonPrefix(x, y)
+	return n == len(x) || n == len(y)
+}
+
+// effect kinds of an operation on a single location
+const (
+	setEffect    = iota // value written over (replace, add of an object member)
+	unsetEffect         // object member removed
+	insertEffect        // array element inserted
+	deleteEffect        // array element removed
+)
+
+type effect struct {
+	kind int
+	at   jsonptr
+	op   string
+}
+
+// role of a pointer within an operation
+const (
+	targetRole = iota // an existing value is read, replaced or removed
+	destRole          // a value is added
+)
+
+// rebase returns x as it must be applied after y, where both were made
+// against the same document.  ok is false when x no longer has any effect.
+func rebase(x, y Operation, xWins bool) (Operation, bool) {
+	xLocs, yLocs := x.locations(), y.locations()
+	if xLocs == nil || yLocs == nil {
+		return x, true
+	}
+
+	if x.Op == "move" {
+		from, ok := relocate(xLocs[1], targetRole, x.Op, y, yLocs, xWins)
+		if !ok {
+			return x, false
+		}
+		// the destination is relative to the document without the source
+		to := xLocs[0]
+		if y2, ok := without(y, xLocs[1]); ok {
+			if to, ok = relocate(to, destRole, x.Op, y2, y2.locations(), xWins); !ok {
+				// the destination is gone, the value still leaves its source
+				return Operation{Op: "remove", Path: from.path()}, true
+			}
+		}
+		xLocs[0], xLocs[1] = to, from
+	} else if x.Op == "copy" {
+		from, ok := relocate(xLocs[1], targetRole, x.Op, y, yLocs, xWins)
+		if !ok {
+			return x, false
+		}
+		to, ok := relocate(xLocs[0], destRole, x.Op, y, yLocs, xWins)
+		if !ok {
+			return x, false
+		}
+		xLocs[0], xLocs[1] = to, from
+	} else {
+		role := destRole
+		if x.Op != "add" {
+			role = targetRole
+		}
+		p, ok := relocate(xLocs[0], role, x.Op, y, yLocs, xWins)
+		if !ok {
+			return x, false
+		}
+		xLocs[0] = p
+	}
+
+	x.Path = xLocs[0].path()
+	if len(xLocs) > 1 {
+		x.From = xLocs[1].path()
+	}
+	return x, true
+}
+
+// without returns y as it applies to the document without the value at ptr.
+func without(y Operation, ptr jsonptr) (Operation, bool) {
+	rm := Operation{Op: "remove", Path: ptr.path()}
+	if y.Op == "move" || y.Op == "copy" {
+		if from := y.locations()[1]; below(from, ptr) || (y.Op == "copy" && equal(from, ptr)) {
+			// the value y moves or copies is taken from the one removed
+			to, ok := relocate(y.locations()[0], destRole, "add", rm, []jsonptr{ptr}, false)
+			return Operation{Op: "add", Path: to.path()}, ok
+		}
+	}
+	return rebase(y, rm, false)
+}
+
+// relocate returns p as seen after y has been applied.
+func relocate(p jsonptr, role int, xop string, y Operation, yLocs []jsonptr, wins bool) (jsonptr, bool) {
+	if y.Op == "move" {
+		to, from := yLocs[0], yLocs[1]
+		if below(p, from) || (role == targetRole && equal(p, from)) {
+			// follow the moved value
+			moved := make(jsonptr, 0, len(to)+len(p)-len(from))
+			moved = append(moved, to...)
+			return append(moved, p[len(from):]...), true
+		}
+		p, ok := shift(p, role, xop, removal(from, "move"), wins)
+		if !ok {
+			return nil, false
+		}
+		return shift(p, role, xop, addition(to, "move"), wins)
+	}
+
+	var e effect
+	switch y.Op {
+	case "add", "copy":
+		e = addition(yLocs[0], y.Op)
+	case "remove":
+		e = removal(yLocs[0], y.Op)
+	case "replace":
+		e = effect{setEffect, yLocs[0], y.Op}
+	default:
+		return p, true
+	}
+	return shift(p, role, xop, e, wins)
+}
+
+func addition(at jsonptr, op string) effect {
+	if len(at) > 0 && isIndex(at[len(at)-1]) {
+		return effect{insertEffect, at, op}
+	}
+	return effect{setEffect, at, op}
+}
+
+func removal(at jsonptr, op string) effect {
+	if len(at) > 0 && isIndex(at[len(at)-1]) {
+		return effect{deleteEffect, at, op}
+	}
+	return effect{unsetEffect, at, op}
+}
+
+// shift returns p as seen after the effect e.  ok is false when the location
+// p refers to no longer exists or lost a conflict.
+func shift(p jsonptr, role int, xop string, e effect, wins bool) (jsonptr, bool) {
+	n := len(e.at)
+
+	switch e.kind {
+	case setEffect, unsetEffect:
+		if len(p) < n || commonPrefix(p, e.at) != n {
+			return p, true
+		}
+		if len(p) > n {
+			return nil, false
+		}
+		if e.kind == unsetEffect {
+			// only an add can recreate the member
+			return p, role == destRole
+		}
+		if role == destRole && n > 0 && isIndex(p[n-1]) {
+			// inserting next to a replaced element
+			return p, true
+		}
+		switch {
+		case xop == "add" || xop == "replace" || ((xop == "move" || xop == "copy") && role == destRole):
+			return p, wins
+		case xop == "remove":
+			return p, e.op == "replace"
+		}
+		return p, true
+
+	case insertEffect, deleteEffect:
+		if len(p) < n || commonPrefix(p, e.at[:n-1]) != n-1 {
+			return p, true
+		}
+		j, err := strconv.Atoi(string(p[n-1]))
+		if err != nil {
+			return p, true
+		}
+		i, err := strconv.Atoi(string(e.at[n-1]))
+		if err != nil {
+			// appended with "-", every existing index stays valid
+			return p, true
+		}
+		insert := role == destRole && len(p) == n
+		if e.kind == insertEffect {
+			if j > i || (j == i && !(insert && wins)) {
+				j++
+			}
+		} else {
+			if j == i && !insert {
+				return nil, false
+			}
+			if j > i {
+				j--
+			}
+		}
+		shifted := make(jsonptr, len(p))
+		copy(shifted, p)
+		shifted[n-1] = reftoken(strconv.Itoa(j))
+		return shifted, true
+	}
+	return p, true
+}
+
+func equal(x, y jsonptr) bool {
+	return len(x) == len(y) && commonPrefix(x, y) == len(x)
+}
@@ -0,0 +1,312 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_Transform_Converges(t *testing.T) {
+	tests := []struct {
+		doc, a, b, expected string
+	}{
+		{
+			doc:      `{"l": ["x", "y", "z"]}`,
+			a:        `[{"op": "add", "path": "/l/1", "value": "a"}]`,
+			b:        `[{"op": "replace", "path": "/l/2", "value": "b"}]`,
+			expected: `{"l": ["x", "a", "y", "b"]}`,
+		},
+		{
+			doc:      `{"l": ["x", "y", "z"]}`,
+			a:        `[{"op": "remove", "path": "/l/0"}]`,
+			b:        `[{"op": "add", "path": "/l/2", "value": "b"}, {"op": "replace", "path": "/l/1", "value": "Y"}]`,
+			expected: `{"l": ["Y", "b", "z"]}`,
+		},
+		{
+			doc:      `{"l": ["x", "y"]}`,
+			a:        `[{"op": "add", "path": "/l/1", "value": "a"}]`,
+			b:        `[{"op": "add", "path": "/l/1", "value": "b"}]`,
+			expected: `{"l": ["x", "a", "b", "y"]}`,
+		},
+		{
+			doc:      `{"l": ["x", "y", "z"]}`,
+			a:        `[{"op": "remove", "path": "/l/1"}]`,
+			b:        `[{"op": "remove", "path": "/l/1"}]`,
+			expected: `{"l": ["x", "z"]}`,
+		},
+		{
+			doc:      `{"a": {"b": 1}, "c": 1}`,
+			a:        `[{"op": "remove", "path": "/a"}]`,
+			b:        `[{"op": "replace", "path": "/a/b", "value": 2}, {"op": "replace", "path": "/c", "value": 2}]`,
+			expected: `{"c": 2}`,
+		},
+		{
+			doc:      `{"a": 1}`,
+			a:        `[{"op": "replace", "path": "/a", "value": "a"}]`,
+			b:        `[{"op": "replace", "path": "/a", "value": "b"}]`,
+			expected: `{"a": "a"}`,
+		},
+		{
+			doc:      `{"a": 1}`,
+			a:        `[{"op": "replace", "path": "/a", "value": "a"}]`,
+			b:        `[{"op": "remove", "path": "/a"}]`,
+			expected: `{}`,
+		},
+		{
+			doc:      `{"a": {"b": 1}, "c": {}}`,
+			a:        `[{"op": "move", "from": "/a", "path": "/c/a"}]`,
+			b:        `[{"op": "replace", "path": "/a/b", "value": 2}]`,
+			expected: `{"c": {"a": {"b": 2}}}`,
+		},
+		{
+			doc:      `{"a": 1, "c": {}}`,
+			a:        `[{"op": "move", "from": "/a", "path": "/c/a"}]`,
+			b:        `[{"op": "remove", "path": "/c"}]`,
+			expected: `{}`,
+		},
+		{
+			doc:      `{"l": [0, 1, 2, 3]}`,
+			a:        `[{"op": "move", "from": "/l/0", "path": "/l/3"}]`,
+			b:        `[{"op": "replace", "path": "/l/2", "value": "b"}, {"op": "remove", "path": "/l/3"}]`,
+			expected: `{"l": [1, "b", 0]}`,
+		},
+		{
+			doc:      `{"b": {"b": 0}, "l": [0, 1, 2]}`,
+			a:        `[{"op": "move", "from": "/b/b", "path": "/l/0"}]`,
+			b:        `[{"op": "remove", "path": "/l/2"}, {"op": "remove", "path": "/b"}]`,
+			expected: `{"l": [0, 1]}`,
+		},
+		{
+			doc:      `{"a": {"n": 1}, "l": []}`,
+			a:        `[{"op": "copy", "from": "/a", "path": "/d"}, {"op": "remove", "path": "/l"}]`,
+			b:        `[{"op": "copy", "from": "/l", "path": "/a/a"}]`,
+			expected: `{"a": {"n": 1}}`,
+		},
+		{
+			doc:      `{"a": 1, "b": {}, "c": 2}`,
+			a:        `[{"op": "move", "from": "/a", "path": "/b/a"}]`,
+			b:        `[{"op": "move", "from": "/a", "path": "/c"}]`,
+			expected: `{"b": {"a": 1}}`,
+		},
+		{
+			doc:      `{"a": "A", "l": ["x", "y"]}`,
+			a:        `[{"op": "copy", "from": "/a", "path": "/l/0"}]`,
+			b:        `[{"op": "replace", "path": "/l/0", "value": "b"}]`,
+			expected: `{"a": "A", "l": ["A", "b", "y"]}`,
+		},
+		// a non-standard operation is lost with a concurrent change of its value
+		{
+			doc:      `{"n": 1, "m": 1}`,
+			a:        `[{"op": "replace", "path": "/n", "value": 5}]`,
+			b:        `[{"op": "inc", "path": "/n", "value": 1}, {"op": "inc", "path": "/m", "value": 1}]`,
+			expected: `{"m": 2}`,
+		},
+		{
+			doc:      `{"l": [1, 2]}`,
+			a:        `[{"op": "remove", "path": "/l/0"}]`,
+			b:        `[{"op": "inc", "path": "/l/1", "value": 1}]`,
+			expected: `{"l": [3]}`,
+		},
+	}
+
+	ps := &Parser{Extended: true}
+	for i, test := range tests {
+		a, err := ps.Parse(strings.NewReader(test.a))
+		if err != nil {
+			t.Fatalf("%d: Failed parsing: %q. %s", i, test.a, err)
+		}
+		b, err := ps.Parse(strings.NewReader(test.b))
+		if err != nil {
+			t.Fatalf("%d: Failed parsing: %q. %s", i, test.b, err)
+		}
+		a2, b2 := Transform(a, b)
+
+		ab := applyAll(t, test.doc, a, b2)
+		ba := applyAll(t, test.doc, b, a2)
+		if !jsonEqual(ab, ba) {
+			t.Errorf("%d: diverged a,b2 => %s b,a2 => %s", i, ab, ba)
+		}
+		if !jsonEqual(ab, []byte(test.expected)) {
+			t.Errorf("%d: (actual) %s != %s (expected)", i, ab, test.expected)
+		}
+	}
+}
+
+func Test_Transform_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(6902))
+	ops := []string{"add", "remove", "replace", "move", "copy", "wrap"}
+	failed := 0
+	for i := 0; i < 20000 && failed < 5; i++ {
+		doc := randomValue(r, 3)
+		a, b := randomPatch(r, doc, ops), randomPatch(r, doc, ops)
+		for _, tie := range []TieBreak{PreferA, PreferB} {
+			a2, b2 := (&Transformer{Tie: tie}).Transform(a, b)
+			ab, aerr := (&Patcher{ops: append(append([]Operation{}, a.ops...), b2.ops...)}).apply(clone(doc))
+			ba, berr := (&Patcher{ops: append(append([]Operation{}, b.ops...), a2.ops...)}).apply(clone(doc))
+			if aerr != nil || berr != nil || !sameValue(ab, ba) {
+				failed++
+				d, _ := json.Marshal(doc)
+				aj, _ := json.Marshal(a)
+				bj, _ := json.Marshal(b)
+				a2j, _ := json.Marshal(a2)
+				b2j, _ := json.Marshal(b2)
+				abj, _ := json.Marshal(ab)
+				baj, _ := json.Marshal(ba)
+				t.Errorf("%d: doc %s a %s b %s tie %d\na2 %s b2 %s\na,b2 => %s %v\nb,a2 => %s %v",
+					i, d, aj, bj, tie, a2j, b2j, abj, aerr, baj, berr)
+			}
+		}
+	}
+}
+
+// wrap replaces the value at path by an array holding it, standing for any
+// non-standard operation.
+type wrap struct{}
+
+func (wrap) Members() []string { return nil }
+
+func (wrap) Apply(doc *Document, path Pointer, op Operation) error {
+	v, err := doc.Get(path)
+	if err != nil {
+		return err
+	}
+	return doc.Replace(path, []interface{}{v})
+}
+
+// randomValue returns a small JSON value nested at most depth levels deep.
+func randomValue(r *rand.Rand, depth int) interface{} {
+	switch n := r.Intn(4); {
+	case depth <= 0 || n == 0:
+		return float64(r.Intn(10))
+	case n == 1:
+		a := make([]interface{}, r.Intn(4))
+		for i := range a {
+			a[i] = randomValue(r, depth-1)
+		}
+		return a
+	}
+	m := make(map[string]interface{})
+	for i := r.Intn(4); i > 0; i-- {
+		m[string(rune('a'+r.Intn(4)))] = randomValue(r, depth-1)
+	}
+	return m
+}
+
+// randomPatch returns up to three random operations out of ops applying to
+// doc one after the other.
+func randomPatch(r *rand.Rand, doc interface{}, ops []string) *Patcher {
+	p := new(Patcher)
+	doc = clone(doc)
+	for n := 1 + r.Intn(3); len(p.ops) < n; {
+		op, ok := randomOp(r, doc, ops[r.Intn(len(ops))])
+		if !ok {
+			continue
+		}
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			panic(err)
+		}
+		p.ops = append(p.ops, op)
+	}
+	return p
+}
+
+func randomOp(r *rand.Rand, doc interface{}, op string) (Operation, bool) {
+	var values []jsonptr
+	locate(jsonptr{}, doc, &values)
+	at := values[r.Intn(len(values))]
+	switch op {
+	case "add":
+		return Operation{Op: op, Path: randomSlot(r, doc, nil).path(), Value: randomValue(r, 1)}, true
+	case "remove":
+		return Operation{Op: op, Path: at.path()}, len(at) > 0
+	case "replace":
+		return Operation{Op: op, Path: at.path(), Value: randomValue(r, 1)}, true
+	case "wrap":
+		return Operation{Op: op, Path: at.path(), handler: wrap{}}, true
+	}
+	if op == "copy" {
+		return Operation{Op: op, From: at.path(), Path: randomSlot(r, doc, nil).path()}, true
+	}
+	if len(at) == 0 {
+		return Operation{}, false
+	}
+	rest, _ := (&Operation{Op: "remove", Path: at.path()}).apply(clone(doc))
+	return Operation{Op: op, From: at.path(), Path: randomSlot(r, rest, at).path()}, true
+}
+
+// randomSlot returns where a value can be added to doc, not below from.
+func randomSlot(r *rand.Rand, doc interface{}, from jsonptr) jsonptr {
+	var values []jsonptr
+	locate(jsonptr{}, doc, &values)
+	for {
+		at := values[r.Intn(len(values))]
+		var slot jsonptr
+		switch v := lookup(at, doc).(type) {
+		case map[string]interface{}:
+			slot = at.child(reftoken(string(rune('a' + r.Intn(4)))))
+		case []interface{}:
+			slot = at.child(reftoken(strconv.Itoa(r.Intn(len(v) + 1))))
+		default:
+			if len(at) > 0 || r.Intn(4) > 0 {
+				continue
+			}
+			slot = at
+		}
+		if from == nil || !below(slot, from) {
+			return slot
+		}
+	}
+}
+
+// locate appends the pointers to v and every value within it.
+func locate(at jsonptr, v interface{}, out *[]jsonptr) {
+	*out = append(*out, at)
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(t) {
+			locate(at.child(reftoken(k)), t[k], out)
+		}
+	case []interface{}:
+		for i, e := range t {
+			locate(at.child(reftoken(strconv.Itoa(i))), e, out)
+		}
+	}
+}
+
+func Test_Transform_TieBreak(t *testing.T) {
+	a, _ := ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/a", "value": "a"}, {"op": "add", "path": "/l/0", "value": "a"}]`))
+	b, _ := ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/a", "value": "b"}, {"op": "add", "path": "/l/0", "value": "b"}]`))
+	doc := `{"a": 1, "l": []}`
+
+	a2, b2 := (&Transformer{Tie: PreferB}).Transform(a, b)
+	ab := applyAll(t, doc, a, b2)
+	ba := applyAll(t, doc, b, a2)
+	expected := `{"a": "b", "l": ["b", "a"]}`
+	if !jsonEqual(ab, []byte(expected)) || !jsonEqual(ba, []byte(expected)) {
+		t.Errorf("(actual) %s and %s != %s (expected)", ab, ba, expected)
+	}
+}
+
+func Test_Transform_Unchanged(t *testing.T) {
+	a, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/l/0", "value": "a"}]`))
+	b, _ := ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/l/0", "value": "b"}]`))
+	Transform(a, b)
+	actual, _ := json.Marshal(b)
+	if !jsonEqual(actual, []byte(`[{"op": "replace", "path": "/l/0", "value": "b"}]`)) {
+		t.Errorf("Transform() modified its arguments: %s", actual)
+	}
+}
+
+func applyAll(t *testing.T, doc string, patches ...*Patcher) []byte {
+	b := []byte(doc)
+	for _, p := range patches {
+		var err error
+		if b, err = p.Apply(b); err != nil {
+			t.Fatalf("Unable to apply patch to %s: %s", doc, err)
+		}
+	}
+	return b
+}
@@ -0,0 +1,137 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Status is the outcome of a single operation in a Report.
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusFailed
+)
+
+func (s Status) String() string {
+	if s == StatusOK {
+		return "ok"
+	}
+	return "failed"
+}
+
+// MarshalText encodes the status as "ok" or "failed".
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Result describes how one operation of a patch applies to a document.
+type Result struct {
+	Index     int
+	Operation Operation
+	Status    Status
+	// Target is the resolved path of the operation, with "-" replaced by the
+	// array index it stands for.
+	Target string
+	// Old is the value found at Target before the operation: the value
+	// removed or overwritten, or compared by a test.  It is nil when the
+	// operation inserts a new value.
+	Old interface{}
+	Err error
+}
+
+// MarshalJSON encodes the result for use in an error response.
+func (r Result) MarshalJSON() ([]byte, error) {
+	v := struct {
+		Index     int         `json:"index"`
+		Operation Operation   `json:"operation"`
+		Status    Status      `json:"status"`
+		Target    string      `json:"target"`
+		Old       interface{} `json:"old,omitempty"`
+		Error     string      `json:"error,omitempty"`
+	}{r.Index, r.Operation, r.Status, r.Target, r.Old, ""}
+	if r.Err != nil {
+		v.Error = r.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// Report lists the Result of every operation of a patch.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Err returns the error of the first failed operation, or nil.
+func (r *Report) Err() error {
+	for _, res := range r.Results {
+		if res.Status == StatusFailed {
+			return res.Err
+		}
+	}
+	return nil
+}
+
+/*
+Validate reports for every operation whether it applies to the JSON document
+b, without modifying b.
+
+Operations are evaluated in order.  A failed operation is left out, so later
+operations are checked against the document as it is without it.  Apply
+succeeds exactly when Report.Err returns nil.
+*/
+func (p *Patcher) Validate(b []byte) (*Report, error) {
+	if len(b) <= 0 {
+		return nil, errors.New("rfc6902: empty JSON document")
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	r := &Report{Results: make([]Result, len(p.ops))}
+	for i, o := range p.ops {
+		res := Result{Index: i, Operation: o, Target: o.Path}
+		if ptr, err := newJSONPointer(o.Path); err == nil {
+			target := resolve(ptr, doc)
+			res.Target = target.path()
+			res.Old = previous(o.Op, target, doc)
+		}
+
+		v, err := o.apply(clone(doc))
+		if err != nil {
+			res.Status, res.Err = StatusFailed, err
+		} else {
+			doc = v
+		}
+		r.Results[i] = res
+	}
+	return r, nil
+}
+
+// resolve replaces a trailing "-" with the index past the end of the array.
+func resolve(ptr jsonptr, doc interface{}) jsonptr {
+	if len(ptr) == 0 || ptr[len(ptr)-1] != "-" {
+		return ptr
+	}
+	parent := ptr[:len(ptr)-1]
+	if a, ok := lookup(parent, doc).([]interface{}); ok {
+		return parent.child(indexToken(len(a)))
+	}
+	return ptr
+}
+
+// previous returns the value an operation of kind op removes, overwrites or
+// compares at target.
+func previous(op string, target jsonptr, doc interface{}) interface{} {
+	switch op {
+	case "add", "move", "copy":
+		// inserting into an array does not overwrite anything
+		if len(target) == 0 {
+			return doc
+		}
+		if _, ok := lookup(target[:len(target)-1], doc).(map[string]interface{}); !ok {
+			return nil
+		}
+	}
+	return lookup(target, doc)
+}
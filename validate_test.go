@@ -0,0 +1,91 @@
+package rfc6902
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Validate(t *testing.T) {
+	doc := `{"a": 1, "l": ["x", "y"], "o": {"k": "v"}}`
+	patch := `[
+		{"op": "replace", "path": "/a", "value": 2},
+		{"op": "add", "path": "/l/-", "value": "z"},
+		{"op": "remove", "path": "/missing"},
+		{"op": "add", "path": "/o/k", "value": "w"},
+		{"op": "test", "path": "/a", "value": 1},
+		{"op": "move", "from": "/l/0", "path": "/o/first"},
+		{"op": "remove", "path": "/l/1"}
+	]`
+	expected := []struct {
+		status Status
+		target string
+		old    interface{}
+	}{
+		{StatusOK, "/a", 1.0},
+		{StatusOK, "/l/2", nil},
+		{StatusFailed, "/missing", nil},
+		{StatusOK, "/o/k", "v"},
+		{StatusFailed, "/a", 2.0},
+		{StatusOK, "/o/first", nil},
+		{StatusOK, "/l/1", "z"},
+	}
+
+	p, err := ParsePatch(strings.NewReader(patch))
+	if err != nil {
+		t.Fatalf("Failed parsing: %q. %s", patch, err)
+	}
+	r, err := p.Validate([]byte(doc))
+	if err != nil {
+		t.Fatalf("Validate failed: %s", err)
+	}
+	if len(r.Results) != len(expected) {
+		t.Fatalf("(actual) %d != %d (expected) results", len(r.Results), len(expected))
+	}
+	for i, e := range expected {
+		res := r.Results[i]
+		if res.Index != i || res.Status != e.status || res.Target != e.target || !reflect.DeepEqual(res.Old, e.old) {
+			t.Errorf("%d: (actual) %s %s %v != %s %s %v (expected)", i, res.Status, res.Target, res.Old, e.status, e.target, e.old)
+		}
+		if (res.Err != nil) != (e.status == StatusFailed) {
+			t.Errorf("%d: unexpected error %v", i, res.Err)
+		}
+	}
+	if r.Err() != ErrorInvalidJSONPath {
+		t.Errorf("Err() (actual) %v != %v (expected)", r.Err(), ErrorInvalidJSONPath)
+	}
+	if r.Results[4].Err != ErrorTestFailed {
+		t.Errorf("test (actual) %v != %v (expected)", r.Results[4].Err, ErrorTestFailed)
+	}
+}
+
+func Test_Validate_MatchesApply(t *testing.T) {
+	doc := []byte(`{"a": {"b": [1, 2]}}`)
+	p, _ := ParsePatch(strings.NewReader(`[{"op": "add", "path": "/a/b/0", "value": 0}, {"op": "move", "from": "/a/b", "path": "/c"}]`))
+	r, err := p.Validate(doc)
+	if err != nil {
+		t.Fatalf("Validate failed: %s", err)
+	}
+	if r.Err() != nil {
+		t.Errorf("Validate reported %s", r.Err())
+	}
+	if !jsonEqual(doc, []byte(`{"a": {"b": [1, 2]}}`)) {
+		t.Errorf("Validate modified the document: %s", doc)
+	}
+	if _, err := p.Apply(doc); err != nil {
+		t.Errorf("Apply failed after successful validation: %s", err)
+	}
+}
+
+func Test_Result_MarshalJSON(t *testing.T) {
+	r := Result{Index: 1, Operation: Operation{Op: "remove", Path: "/a"}, Status: StatusFailed, Target: "/a", Err: ErrorInvalidJSONPath}
+	actual, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	expected := `{"index": 1, "operation": {"op": "remove", "path": "/a"}, "status": "failed", "target": "/a", "error": "Invalid JSON Path"}`
+	if !jsonEqual(actual, []byte(expected)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, expected)
+	}
+}
@@ -0,0 +1,463 @@
+/*
+Package yamlpatch applies JSON Patch documents (RFC 6902) to YAML documents
+and reads patches written in YAML.
+
+The document is patched as the JSON value it stands for: map keys become
+strings, numbers float64, and aliases and merge keys are resolved.  Comments,
+key order and scalar styles are kept for everything the patch leaves in
+place; new object members are added after the existing ones.  An anchored
+value that changes is written out in full wherever it was referenced.
+
+YAML support is the function Apply rather than a Patcher.ApplyYAML method so
+that the rfc6902 package keeps no dependency outside the standard library;
+only programs that import yamlpatch need gopkg.in/yaml.v3.
+*/
+package yamlpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/noahcampbell/rfc6902"
+	"gopkg.in/yaml.v3"
+)
+
+// Apply applies p to the YAML document b and returns the patched document as
+// YAML.
+func Apply(p *rfc6902.Patcher, b []byte) ([]byte, error) {
+	return ApplyWithOptions(p, b, nil)
+}
+
+// ApplyWithOptions applies p to the YAML document b like Apply, customised by
+// o.  The codec of o is ignored.
+func ApplyWithOptions(p *rfc6902.Patcher, b []byte, o *rfc6902.ApplyOptions) ([]byte, error) {
+	var opts rfc6902.ApplyOptions
+	if o != nil {
+		opts = *o
+	}
+	opts.Codec = new(codec)
+	return p.ApplyWithOptions(b, &opts)
+}
+
+// codec is the rfc6902.Codec of a single document.  Encode writes the value
+// into the nodes Decode read.
+type codec struct {
+	root yaml.Node
+	decoder
+}
+
+func (c *codec) Decode(b []byte) (interface{}, error) {
+	if err := yaml.Unmarshal(b, &c.root); err != nil {
+		return nil, err
+	}
+	if len(c.root.Content) == 0 {
+		return nil, errors.New("rfc6902: empty YAML document")
+	}
+	v, err := c.fromYAML(c.root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	// the patch modifies the value in place
+	return copyValue(v), nil
+}
+
+func (c *codec) Encode(v interface{}) ([]byte, error) {
+	if len(c.root.Content) == 0 {
+		return nil, errors.New("rfc6902: empty YAML document")
+	}
+	n, err := c.reconcile(c.root.Content[0], v)
+	if err != nil {
+		return nil, err
+	}
+	c.root.Content[0] = n
+	unalias(&c.root, make(map[*yaml.Node]bool))
+
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&c.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParsePatch reads a patch document written in YAML from r.
+func ParsePatch(r io.Reader) (*rfc6902.Patcher, error) {
+	return Parse(new(rfc6902.Parser), r)
+}
+
+// Parse reads a patch document written in YAML from r with ps.  Since JSON is
+// YAML it reads JSON patch documents as well.
+func Parse(ps *rfc6902.Parser, r io.Reader) (*rfc6902.Patcher, error) {
+	if r == nil {
+		return nil, errors.New("reader is nil")
+	}
+	var root yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, errors.New("rfc6902: empty YAML document")
+	}
+	v, err := new(decoder).fromYAML(root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ps.Parse(bytes.NewReader(b))
+}
+
+// maxExpansion bounds the nodes aliases add to a document, so a small
+// document cannot expand into a huge value.
+const maxExpansion = 1 << 16
+
+// decoder converts nodes to the values encoding/json decodes to.  Values are
+// cached by node, so aliases and the comparisons of reconcile do not convert
+// a node again; they share the cached values, which must not be modified.
+type decoder struct {
+	values map[*yaml.Node]decoded
+	// active holds the nodes being converted, to detect aliases of an
+	// enclosing node.
+	active map[*yaml.Node]bool
+}
+
+type decoded struct {
+	v interface{}
+	// size is the number of nodes of the value with every alias expanded.
+	size int
+}
+
+// fromYAML returns the value of n in the form encoding/json decodes to.  It
+// fails for aliases of an enclosing node and for values aliases expand by
+// more than maxExpansion nodes.
+func (d *decoder) fromYAML(n *yaml.Node) (interface{}, error) {
+	if d.values == nil {
+		d.values = make(map[*yaml.Node]decoded)
+		d.active = make(map[*yaml.Node]bool)
+	}
+	r, err := d.decode(n)
+	if err != nil {
+		return nil, err
+	}
+	if r.size > len(d.values)+maxExpansion {
+		return nil, fmt.Errorf("rfc6902: aliases expand the YAML document by more than %d nodes", maxExpansion)
+	}
+	return r.v, nil
+}
+
+func (d *decoder) decode(n *yaml.Node) (decoded, error) {
+	if r, ok := d.values[n]; ok {
+		return r, nil
+	}
+	if d.active[n] {
+		return decoded{}, fmt.Errorf("rfc6902: YAML value at line %d contains an alias of itself", n.Line)
+	}
+	d.active[n] = true
+	r, err := d.convert(n)
+	delete(d.active, n)
+	if err != nil {
+		return decoded{}, err
+	}
+	d.values[n] = r
+	return r, nil
+}
+
+func (d *decoder) convert(n *yaml.Node) (decoded, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		return d.decode(n.Content[0])
+	case yaml.AliasNode:
+		return d.decode(n.Alias)
+	case yaml.SequenceNode:
+		a := make([]interface{}, len(n.Content))
+		size := 1
+		for i, c := range n.Content {
+			r, err := d.decode(c)
+			if err != nil {
+				return decoded{}, err
+			}
+			a[i] = r.v
+			size += r.size
+		}
+		return decoded{a, size}, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		size := 1
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, c := n.Content[i], n.Content[i+1]
+			if k.Tag == "!!merge" {
+				merged = append(merged, c)
+				continue
+			}
+			key, err := yamlKey(k)
+			if err != nil {
+				return decoded{}, err
+			}
+			r, err := d.decode(c)
+			if err != nil {
+				return decoded{}, err
+			}
+			m[key] = r.v
+			size += r.size
+		}
+		// explicit keys take precedence over merged ones
+		for _, c := range merged {
+			if c.Kind == yaml.AliasNode {
+				c = c.Alias
+			}
+			sources := []*yaml.Node{c}
+			if c.Kind == yaml.SequenceNode {
+				sources = c.Content
+			}
+			for _, s := range sources {
+				r, err := d.decode(s)
+				if err != nil {
+					return decoded{}, err
+				}
+				mm, ok := r.v.(map[string]interface{})
+				if !ok {
+					return decoded{}, fmt.Errorf("rfc6902: merge of a non-mapping at line %d", s.Line)
+				}
+				for key, value := range mm {
+					if _, ok := m[key]; !ok {
+						m[key] = value
+					}
+				}
+				size += r.size
+			}
+		}
+		return decoded{m, size}, nil
+	}
+
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return decoded{}, err
+	}
+	switch x := v.(type) {
+	case int:
+		v = float64(x)
+	case int64:
+		v = float64(x)
+	case uint64:
+		v = float64(x)
+	case time.Time, []byte:
+		v = n.Value
+	}
+	return decoded{v, 1}, nil
+}
+
+// copyValue returns a deep copy of v, which may share values between aliases.
+func copyValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		a := make([]interface{}, len(x))
+		for i, e := range x {
+			a[i] = copyValue(e)
+		}
+		return a
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[k] = copyValue(e)
+		}
+		return m
+	}
+	return v
+}
+
+// yamlKey returns the object member name of the mapping key k.
+func yamlKey(k *yaml.Node) (string, error) {
+	if k.Kind == yaml.AliasNode {
+		k = k.Alias
+	}
+	if k.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("rfc6902: unsupported mapping key at line %d", k.Line)
+	}
+	return k.Value, nil
+}
+
+// reconcile returns a node for v, reusing n where it already holds the same
+// value.  n is not modified and may be nil.
+func (d *decoder) reconcile(n *yaml.Node, v interface{}) (*yaml.Node, error) {
+	if n != nil && d.holds(n, v) {
+		return n, nil
+	}
+
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if n == nil || n.Kind != yaml.MappingNode {
+			break
+		}
+		m := *n
+		m.Anchor = ""
+		m.Content = make([]*yaml.Node, 0, 2*len(x))
+		seen := make(map[string]bool, len(x))
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			key, err := yamlKey(k)
+			if err != nil || k.Tag == "!!merge" || seen[key] {
+				continue
+			}
+			value, ok := x[key]
+			if !ok {
+				continue
+			}
+			seen[key] = true
+			c, err := d.reconcile(n.Content[i+1], value)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, k, c)
+		}
+		keys := make([]string, 0, len(x))
+		for key := range x {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			}
+			c, err := d.reconcile(nil, x[key])
+			if err != nil {
+				return nil, err
+			}
+			k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			m.Content = append(m.Content, k, c)
+		}
+		return &m, nil
+
+	case []interface{}:
+		if n == nil || n.Kind != yaml.SequenceNode {
+			break
+		}
+		s := *n
+		s.Anchor = ""
+		s.Content = make([]*yaml.Node, 0, len(x))
+		var ps positions
+		i := 0
+		for j, e := range x {
+			// keep unchanged elements, looking further only when the element
+			// at the same position changed, and reuse the next one if it was
+			// modified
+			if i < len(n.Content) && d.holds(n.Content[i], e) {
+				s.Content = append(s.Content, n.Content[i])
+				i++
+				continue
+			}
+			if ps == nil {
+				ps = d.index(n.Content)
+			}
+			if k := ps.next(e, i); k >= 0 {
+				s.Content = append(s.Content, n.Content[k])
+				i = k + 1
+				continue
+			}
+			var old *yaml.Node
+			if i < len(n.Content) && (j+1 >= len(x) || !d.holds(n.Content[i], x[j+1])) {
+				old = n.Content[i]
+				i++
+			}
+			c, err := d.reconcile(old, e)
+			if err != nil {
+				return nil, err
+			}
+			s.Content = append(s.Content, c)
+		}
+		return &s, nil
+	}
+
+	c := new(yaml.Node)
+	if err := c.Encode(v); err != nil {
+		return nil, err
+	}
+	if n != nil {
+		c.HeadComment, c.LineComment, c.FootComment = n.HeadComment, n.LineComment, n.FootComment
+		if n.Kind == yaml.ScalarNode && c.Kind == yaml.ScalarNode && n.Tag == c.Tag {
+			c.Style = n.Style
+		}
+	}
+	return c, nil
+}
+
+// holds reports whether n stands for v.
+func (d *decoder) holds(n *yaml.Node, v interface{}) bool {
+	old, err := d.fromYAML(n)
+	return err == nil && reflect.DeepEqual(old, v)
+}
+
+// positions lists the indices of the nodes of a sequence by the JSON encoding
+// of their value, so an element is found without comparing it to every node.
+type positions map[string][]int
+
+func (d *decoder) index(ns []*yaml.Node) positions {
+	ps := make(positions, len(ns))
+	for i, n := range ns {
+		v, err := d.fromYAML(n)
+		if err != nil {
+			continue
+		}
+		if b, err := json.Marshal(v); err == nil {
+			ps[string(b)] = append(ps[string(b)], i)
+		}
+	}
+	return ps
+}
+
+// next returns the index of the first node from i on holding v, or -1.
+func (ps positions) next(v interface{}, i int) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return -1
+	}
+	at := ps[string(b)]
+	for len(at) > 0 && at[0] < i {
+		at = at[1:]
+	}
+	ps[string(b)] = at
+	if len(at) == 0 {
+		return -1
+	}
+	return at[0]
+}
+
+// unalias replaces aliases whose anchor is not written before them with a
+// copy of the aliased value.
+func unalias(n *yaml.Node, anchors map[*yaml.Node]bool) {
+	if n.Anchor != "" {
+		anchors[n] = true
+	}
+	for i, c := range n.Content {
+		if c.Kind == yaml.AliasNode && !anchors[c.Alias] {
+			cp := copyNode(c.Alias)
+			cp.HeadComment, cp.LineComment, cp.FootComment = c.HeadComment, c.LineComment, c.FootComment
+			n.Content[i] = cp
+			c = cp
+		}
+		unalias(c, anchors)
+	}
+}
+
+// copyNode returns a deep copy of n without anchors.
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Anchor = ""
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
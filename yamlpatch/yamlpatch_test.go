@@ -0,0 +1,238 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noahcampbell/rfc6902"
+)
+
+func Test_Apply(t *testing.T) {
+	tests := []struct {
+		doc, patch, expected string
+	}{
+		// comments and key order are kept, new members go last
+		{`# deployment
+kind: Deployment
+metadata:
+  name: web # the name
+  labels:
+    app: web
+spec:
+  replicas: 1
+`, `[{"op": "replace", "path": "/spec/replicas", "value": 3}, {"op": "add", "path": "/metadata/labels/tier", "value": "front"}]`,
+			`# deployment
+kind: Deployment
+metadata:
+  name: web # the name
+  labels:
+    app: web
+    tier: front
+spec:
+  replicas: 3
+`},
+		// comments stay with their element when another is removed
+		{`ports:
+  - 80 # http
+  - 443 # https
+`, `[{"op": "remove", "path": "/ports/0"}, {"op": "add", "path": "/ports/-", "value": 8080}]`,
+			`ports:
+  - 443 # https
+  - 8080
+`},
+		// a modified scalar keeps its style and comment
+		{`image: "nginx:1.0" # pinned
+`, `[{"op": "replace", "path": "/image", "value": "nginx:1.1"}]`,
+			`image: "nginx:1.1" # pinned
+`},
+		// keys are normalized to strings
+		{`1: one
+true: x
+`, `[{"op": "test", "path": "/1", "value": "one"}, {"op": "move", "from": "/true", "path": "/t"}]`,
+			`1: one
+t: x
+`},
+		// aliases of unchanged anchors are kept
+		{`base: &b
+  a: 1
+copy: *b
+other: 2
+`, `[{"op": "remove", "path": "/other"}]`,
+			`base: &b
+  a: 1
+copy: *b
+`},
+		// a changed anchor is expanded where it was referenced
+		{`base: &b
+  a: 1
+copy: *b
+`, `[{"op": "replace", "path": "/base/a", "value": 2}]`,
+			`base:
+  a: 2
+copy:
+  a: 1
+`},
+		// merge keys are resolved
+		{`defaults: &d
+  a: 1
+item:
+  <<: *d
+  b: 2
+`, `[{"op": "test", "path": "/item/a", "value": 1}, {"op": "add", "path": "/item/c", "value": 3}]`,
+			`defaults: &d
+  a: 1
+item:
+  b: 2
+  a: 1
+  c: 3
+`},
+	}
+
+	for i, test := range tests {
+		p, err := rfc6902.ParsePatch(strings.NewReader(test.patch))
+		if err != nil {
+			t.Fatalf("%d: ParsePatch failed: %s", i, err)
+		}
+		actual, err := Apply(p, []byte(test.doc))
+		if err != nil {
+			t.Errorf("%d: Apply failed: %s", i, err)
+			continue
+		}
+		if string(actual) != test.expected {
+			t.Errorf("%d: (actual)\n%s\n!= (expected)\n%s", i, actual, test.expected)
+		}
+	}
+}
+
+func Test_Apply_Errors(t *testing.T) {
+	p, _ := rfc6902.ParsePatch(strings.NewReader(`[{"op": "remove", "path": "/x"}]`))
+	for i, doc := range []string{"", "a: [", "a: 1\n", "? [a]\n: 1\n"} {
+		if _, err := Apply(p, []byte(doc)); err == nil {
+			t.Errorf("%d: expected an error for %q", i, doc)
+		}
+	}
+}
+
+func Test_ParsePatch(t *testing.T) {
+	p, err := ParsePatch(strings.NewReader(`
+- op: add
+  path: /a
+  value: {b: [1, two]}
+- {op: remove, path: /c}
+`))
+	if err != nil {
+		t.Fatalf("ParsePatch failed: %s", err)
+	}
+	actual, err := p.Apply([]byte(`{"c": null}`))
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if !jsonEqual(actual, []byte(`{"a": {"b": [1, "two"]}}`)) {
+		t.Errorf("(actual) %s != %s (expected)", actual, `{"a": {"b": [1, "two"]}}`)
+	}
+
+	if _, err := ParsePatch(strings.NewReader(`- op: add`)); err == nil {
+		t.Errorf("expected an error for a patch without path")
+	}
+}
+
+func Test_ParsePatch_Errors(t *testing.T) {
+	for i, patch := range []string{"", "# nothing\n"} {
+		if _, err := ParsePatch(strings.NewReader(patch)); err == nil {
+			t.Errorf("%d: expected an error for %q", i, patch)
+		}
+	}
+}
+
+func Test_Apply_Aliases(t *testing.T) {
+	p, _ := rfc6902.ParsePatch(strings.NewReader(`[{"op": "add", "path": "/x", "value": 1}]`))
+
+	// a value containing an alias of itself
+	for i, doc := range []string{"a: &a [*a]\n", "a: &a {b: *a}\n", "a: &a {<<: *a}\n"} {
+		if _, err := Apply(p, []byte(doc)); err == nil {
+			t.Errorf("%d: expected an error for %q", i, doc)
+		}
+	}
+	if _, err := ParsePatch(strings.NewReader("- &a {op: add, path: /x, value: [*a]}\n")); err == nil {
+		t.Errorf("expected an error for a patch containing itself")
+	}
+
+	// billion laughs
+	var b strings.Builder
+	b.WriteString("l0: &l0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i <= 7; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [", i, i)
+		for j := 0; j < 10; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "*l%d", i-1)
+		}
+		b.WriteString("]\n")
+	}
+	start := time.Now()
+	if _, err := Apply(p, []byte(b.String())); err == nil {
+		t.Errorf("expected an error for a document expanding to 10^8 nodes")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("rejecting the document took %s", d)
+	}
+
+	// moderate use of aliases is fine
+	actual, err := Apply(p, []byte("a: &a [1, 2]\nb: [*a, *a]\n"))
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if expected := "a: &a [1, 2]\nb: [*a, *a]\nx: 1\n"; string(actual) != expected {
+		t.Errorf("(actual) %q != %q (expected)", actual, expected)
+	}
+}
+
+func Test_ApplyWithOptions(t *testing.T) {
+	p, _ := rfc6902.ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/a", "value": 2}]`))
+	o := &rfc6902.ApplyOptions{Codec: rfc6902.JSON, Policy: &rfc6902.Policy{Rules: []rfc6902.Rule{{Effect: rfc6902.Deny, Pattern: "/a"}}}}
+	if _, err := ApplyWithOptions(p, []byte("a: 1\n"), o); err == nil {
+		t.Errorf("expected a policy violation")
+	}
+}
+
+func Test_Apply_LongSequence(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("l:\n")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, "  - %d # %d\n", i, i)
+	}
+	// every element moves
+	p, _ := rfc6902.ParsePatch(strings.NewReader(`[{"op": "add", "path": "/l/0", "value": -1}]`))
+	actual, err := Apply(p, []byte(b.String()))
+	if err != nil {
+		t.Fatalf("Apply failed: %s", err)
+	}
+	if !strings.HasPrefix(string(actual), "l:\n  - -1\n  - 0 # 0\n") || !strings.HasSuffix(string(actual), "  - 19999 # 19999\n") {
+		t.Errorf("(actual) %.40s...%s != elements with their comments (expected)", actual, actual[len(actual)-20:])
+	}
+
+	// every element changes
+	p, _ = rfc6902.ParsePatch(strings.NewReader(`[{"op": "replace", "path": "/l/*", "value": 0}]`))
+	if actual, err = ApplyWithOptions(p, []byte(b.String()), &rfc6902.ApplyOptions{Wildcards: true}); err != nil {
+		t.Fatalf("ApplyWithOptions failed: %s", err)
+	}
+	if !strings.HasSuffix(string(actual), "  - 0 # 19998\n  - 0 # 19999\n") {
+		t.Errorf("(actual) ...%s != elements with their comments (expected)", actual[len(actual)-40:])
+	}
+}
+
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}